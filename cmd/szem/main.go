@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 
 	"github.com/lvbu1984/szem-core/internal/api"
@@ -9,19 +11,70 @@ import (
 )
 
 func main() {
-	store, err := lifecycle.OpenSQLite("./data/meta.db")
+	metaBackend := flag.String("meta-backend", "sqlite", "metadata backend: sqlite or badger")
+	storageBackend := flag.String("storage-backend", "mock", "storage backend: mock or file")
+	flag.Parse()
+
+	store, err := openMetaStore(*metaBackend)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer store.Close()
 
-	lifecycle.StartExpirationScheduler(store)
+	adapter, err := openAdapter(*storageBackend)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	replayed, err := lifecycle.ReplayPendingUploads(context.Background(), store, adapter)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("upload wal replay: rolled_forward=%d rolled_back=%d dropped=%d", replayed.RolledForward, replayed.RolledBack, replayed.Dropped)
+
+	expiry := lifecycle.NewExpirationWorker(store, adapter)
+	if err := expiry.Start(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+	defer expiry.Shutdown(context.Background())
 
-	adapter := storage.NewMockAdapter()
+	usage := lifecycle.NewUsageCrawler(store)
+	usage.Start(context.Background())
+	defer usage.Shutdown(context.Background())
 
-	server := api.NewServer(store, adapter)
+	server := api.NewServer(store, adapter, expiry)
 
 	log.Println("Qave API running on :8080")
 	log.Fatal(server.Start(":8080"))
 }
 
+// openMetaStore is the single switch point between metadata backends:
+// everything above MetaStore only ever sees the interface, so this flag is
+// the whole migration.
+func openMetaStore(backend string) (lifecycle.MetaStore, error) {
+	switch backend {
+	case "badger":
+		return lifecycle.OpenBadger("./data/meta.badger")
+	case "sqlite", "":
+		return lifecycle.OpenSQLite("./data/meta.db")
+	default:
+		log.Fatalf("unknown -meta-backend %q (want sqlite or badger)", backend)
+		return nil, nil
+	}
+}
+
+// openAdapter is the same kind of switch point as openMetaStore, but for the
+// storage.Adapter side: everything above storage.Adapter only ever sees the
+// interface, so this flag is the whole migration from the mock adapter to
+// durable on-disk pieces.
+func openAdapter(backend string) (storage.Adapter, error) {
+	switch backend {
+	case "file":
+		return storage.NewFileAdapter("./data/pieces")
+	case "mock", "":
+		return storage.NewMockAdapter(), nil
+	default:
+		log.Fatalf("unknown -storage-backend %q (want mock or file)", backend)
+		return nil, nil
+	}
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net/http"
@@ -11,20 +12,25 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/lvbu1984/szem-core/internal/lifecycle"
+	"github.com/lvbu1984/szem-core/internal/s3"
 	"github.com/lvbu1984/szem-core/internal/storage"
 )
 
 const maxUploadSize = 50 << 20 // 50MB
 
 type Server struct {
-	store   *lifecycle.SQLiteStore
+	store   lifecycle.MetaStore
 	adapter storage.Adapter
+	expiry  *lifecycle.ExpirationWorker
+	s3      *s3.Server
 }
 
-func NewServer(store *lifecycle.SQLiteStore, adapter storage.Adapter) *Server {
+func NewServer(store lifecycle.MetaStore, adapter storage.Adapter, expiry *lifecycle.ExpirationWorker) *Server {
 	return &Server{
 		store:   store,
 		adapter: adapter,
+		expiry:  expiry,
+		s3:      s3.NewServer(store, adapter, expiry),
 	}
 }
 
@@ -36,11 +42,46 @@ func (s *Server) Start(addr string) error {
 	mux.HandleFunc("/object/", s.withMiddleware(s.handleGetObject))
 	mux.HandleFunc("/objects", s.withMiddleware(s.handleListObjects))
 	mux.HandleFunc("/dashboard", s.withMiddleware(s.handleDashboard))
+	mux.HandleFunc("/dashboard/usage", s.withMiddleware(s.handleDashboardUsage))
+	mux.HandleFunc("/credentials", s.withMiddleware(s.handleCredentials))
+
+	// Everything else is treated as S3 path-style addressing,
+	// PUT/GET/DELETE/HEAD /{bucket}/{key} and ListObjectsV2 on /{bucket}.
+	mux.HandleFunc("/", s.withMiddleware(s.s3.Handler))
 
 	log.Println("Qave API running on", addr)
 	return http.ListenAndServe(addr, mux)
 }
 
+// handleCredentials mints a wallet's SigV4 secret key the first time it is
+// requested and returns it. Qave has no out-of-band wallet authentication to
+// gate this on, so the secret is shown exactly once: every request after the
+// first gets a 409, same as AWS never letting you re-download a secret
+// access key. Without this, anyone who names a victim's wallet in X-Wallet
+// could fetch that wallet's secret and forge its signatures.
+func (s *Server) handleCredentials(w http.ResponseWriter, r *http.Request) {
+	wallet := r.Header.Get("X-Wallet")
+	if wallet == "" {
+		writeError(w, http.StatusBadRequest, "missing_wallet", "X-Wallet header required")
+		return
+	}
+
+	secretKey, err := s.store.GetOrCreateCredential(wallet)
+	if errors.Is(err, lifecycle.ErrCredentialAlreadyIssued) {
+		writeError(w, http.StatusConflict, "credential_already_issued", "a credential was already issued for this wallet and cannot be re-fetched")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "credential_error", "failed to mint credential")
+		return
+	}
+
+	writeJSON(w, map[string]string{
+		"access_key_id":     wallet,
+		"secret_access_key": secretKey,
+	})
+}
+
 func (s *Server) withMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		requestID := uuid.New().String()
@@ -93,39 +134,64 @@ func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	now := time.Now().UTC()
+	expire := now.Add(30 * 24 * time.Hour)
+
+	intent := lifecycle.PendingUpload{
+		ID:        uuid.New().String(),
+		Wallet:    wallet,
+		DataSetID: string(dataSetID),
+		ObjectID:  uuid.New().String(),
+		LeaseID:   uuid.New().String(),
+		Size:      int64(len(data)),
+		CreatedAt: now,
+		ExpireAt:  expire,
+	}
+
+	// Record intent before the upload, so a crash between adapter.Upload
+	// and the metadata transaction leaves a journal entry that startup
+	// replay can finish or reclaim, instead of an orphan piece or an
+	// orphan object with no lease.
+	if err := s.store.RecordUploadIntent(intent); err != nil {
+		writeError(w, http.StatusInternalServerError, "upload_error", "failed to record upload intent")
+		return
+	}
+
 	uploadResult, err := s.adapter.Upload(ctx, dataSetID, data, storage.UploadOptions{
 		FileName: "file",
+		Wallet:   wallet,
 	})
 	if err != nil {
+		_ = s.store.DropUploadIntent(intent.ID)
 		writeError(w, http.StatusInternalServerError, "upload_error", "upload failed")
 		return
 	}
+	intent.PieceCID = string(uploadResult.PieceCID)
 
-	objectID := uuid.New().String()
-
-	s.store.InsertUser(wallet)
-	s.store.InsertDataSet(string(dataSetID), wallet)
-	s.store.InsertObject(objectID, wallet, string(dataSetID), int64(len(data)))
-
-	now := time.Now().UTC()
-	expire := now.Add(30 * 24 * time.Hour)
+	if err := s.store.SetUploadIntentPieceCID(intent.ID, intent.PieceCID); err != nil {
+		writeError(w, http.StatusInternalServerError, "upload_error", "failed to record piece cid")
+		return
+	}
+	if err := s.store.CommitUpload(intent); err != nil {
+		writeError(w, http.StatusInternalServerError, "upload_error", "failed to commit upload")
+		return
+	}
 
 	lease := lifecycle.ObjectLease{
-		LeaseID:  uuid.New().String(),
-		ObjectID: objectID,
-		Wallet:   wallet,
+		LeaseID:   intent.LeaseID,
+		ObjectID:  intent.ObjectID,
+		Wallet:    wallet,
 		CreatedAt: now,
 		ExpireAt:  expire,
 		StorageRef: lifecycle.StorageRef{
 			DataSetID: string(dataSetID),
-			PieceCID:  string(uploadResult.PieceCID),
+			PieceCID:  intent.PieceCID,
 		},
 	}
-
-	s.store.InsertLease(lease)
+	s.expiry.NotifyInserted(lease)
 
 	writeJSON(w, map[string]any{
-		"object_id": objectID,
+		"object_id": intent.ObjectID,
 		"piece_cid": uploadResult.PieceCID,
 		"size":      uploadResult.Size,
 		"expire_at": expire,
@@ -163,53 +229,20 @@ func (s *Server) handleListObjects(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rows, err := s.store.DB().Query(`
-SELECT o.object_id, o.size_bytes, l.created_at, l.expire_at, l.deleted_at
-FROM objects o
-JOIN leases l ON o.object_id = l.object_id
-WHERE o.wallet = ?
-ORDER BY l.created_at DESC
-`, wallet)
+	leases, err := s.store.ListObjectsByWallet(wallet)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "db_error", "failed to query objects")
 		return
 	}
-	defer rows.Close()
 
 	var result []map[string]any
-
-	for rows.Next() {
-		var objectID string
-		var size int64
-		var createdStr, expireStr string
-		var deletedStr *string
-
-		rows.Scan(&objectID, &size, &createdStr, &expireStr, &deletedStr)
-
-		createdAt, _ := time.Parse(time.RFC3339Nano, createdStr)
-		expireAt, _ := time.Parse(time.RFC3339Nano, expireStr)
-
-		var deletedAt *time.Time
-		if deletedStr != nil {
-			t, _ := time.Parse(time.RFC3339Nano, *deletedStr)
-			deletedAt = &t
-		}
-
-		lease := lifecycle.ObjectLease{
-			ObjectID: objectID,
-			CreatedAt: createdAt,
-			ExpireAt:  expireAt,
-			DeletedAt: deletedAt,
-		}
-
-		status := lifecycle.CalculateLeaseStatus(lease)
-
+	for _, lease := range leases {
 		result = append(result, map[string]any{
-			"object_id": objectID,
-			"size":      size,
-			"created_at": createdAt,
-			"expire_at":  expireAt,
-			"status":     status,
+			"object_id":  lease.ObjectID,
+			"size":       lease.Size,
+			"created_at": lease.CreatedAt,
+			"expire_at":  lease.ExpireAt,
+			"status":     lifecycle.CalculateLeaseStatus(lease),
 		})
 	}
 
@@ -225,6 +258,22 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, stats)
 }
 
+func (s *Server) handleDashboardUsage(w http.ResponseWriter, r *http.Request) {
+	wallet := r.URL.Query().Get("wallet")
+	if wallet == "" {
+		writeError(w, http.StatusBadRequest, "missing_wallet", "wallet query parameter required")
+		return
+	}
+
+	breakdown, err := s.store.GetUsageBreakdown(wallet)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "usage_error", "failed to get usage breakdown")
+		return
+	}
+
+	writeJSON(w, breakdown)
+}
+
 func writeJSON(w http.ResponseWriter, data any) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(data)
@@ -237,4 +286,3 @@ func writeError(w http.ResponseWriter, code int, errCode, message string) {
 		"message": message,
 	})
 }
-
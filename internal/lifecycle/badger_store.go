@@ -0,0 +1,1064 @@
+package lifecycle
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// BadgerStore is the embedded-KV MetaStore implementation. It exists so a
+// deployment can run without a SQL database at all; cmd/szem picks between
+// this and SQLiteStore with a single -meta-backend flag, and nothing above
+// the MetaStore interface needs to know which one it got.
+//
+// There is no JOIN in Badger, so records that SQLiteStore joins across
+// tables (e.g. an object's size_bytes into its lease) are denormalized into
+// the lease record at InsertLease time instead. Range scans that SQLiteStore
+// does with "ORDER BY ... WHERE x BETWEEN" are done here with prefixed,
+// sort-friendly keys:
+//
+//	lease/<expire_at_unix_nano, zero-padded>/<lease_id>   primary lease record
+//	leaseid/<lease_id>                                    -> primary key (pointer)
+//	leasebucket/<bucket>/<object_key>                     -> lease_id (latest)
+//	leaseobj/<object_id>                                  -> lease_id (latest)
+//	leasewallet/<wallet>/<inverted_created_at>/<lease_id>  -> lease_id (ListObjectsByWallet, newest first)
+//	user/<wallet>                                         -> userRecord
+//	dataset/<dataset_id>                                  -> datasetRecord
+//	object/<wallet>/<object_id>                           -> objectRecord
+//	mpu/<upload_id>                                       -> MultipartUpload
+//	mpupart/<upload_id>/<part_number, zero-padded>        -> MultipartPart
+//	usagejournal/<id, zero-padded>                        -> usageJournalEntry
+//	usagecursor                                           -> int64 cursor
+//	usagecache/<wallet>/<dataset_id>                      -> DatasetUsage
+//	usagedaily/<wallet>/<dataset_id>/<day>                -> int64 new_bytes
+//	bucketowner/<bucket>                                  -> wallet (first-writer-wins)
+type BadgerStore struct {
+	db *badger.DB
+}
+
+var _ MetaStore = (*BadgerStore)(nil)
+
+// OpenBadger opens (creating if necessary) a BadgerStore at path, the
+// BadgerDB equivalent of OpenSQLite.
+func OpenBadger(path string) (*BadgerStore, error) {
+	db, err := badger.Open(badger.DefaultOptions(path))
+	if err != nil {
+		return nil, err
+	}
+	return &BadgerStore{db: db}, nil
+}
+
+func (s *BadgerStore) Close() error {
+	return s.db.Close()
+}
+
+type userRecord struct {
+	CreatedAt time.Time
+	SecretKey string
+}
+
+type datasetRecord struct {
+	Wallet    string
+	CreatedAt time.Time
+}
+
+type objectRecord struct {
+	Wallet    string
+	DataSetID string
+	Size      int64
+	CreatedAt time.Time
+}
+
+func userKey(wallet string) []byte       { return []byte("user/" + wallet) }
+func datasetKey(datasetID string) []byte { return []byte("dataset/" + datasetID) }
+func objectKey(wallet, objectID string) []byte {
+	return []byte("object/" + wallet + "/" + objectID)
+}
+func leaseIDKey(leaseID string) []byte { return []byte("leaseid/" + leaseID) }
+func leaseBucketKey(bucket, key string) []byte {
+	return []byte("leasebucket/" + bucket + "/" + key)
+}
+func leaseObjKey(objectID string) []byte { return []byte("leaseobj/" + objectID) }
+
+// leasePrimaryKey sorts ascending by expiry, which is exactly the order
+// GetActiveLeaseExpirations and the expiration worker's heap seed need.
+func leasePrimaryKey(expireAt time.Time, leaseID string) []byte {
+	return []byte(fmt.Sprintf("lease/%020d/%s", expireAt.UnixNano(), leaseID))
+}
+
+// leaseWalletKey inverts the timestamp so ascending key order is descending
+// creation time, matching ListObjectsByWallet's "newest first" contract
+// without a reverse iterator.
+func leaseWalletKey(wallet string, createdAt time.Time, leaseID string) []byte {
+	inverted := uint64(math.MaxInt64) - uint64(createdAt.UnixNano())
+	return []byte(fmt.Sprintf("leasewallet/%s/%020d/%s", wallet, inverted, leaseID))
+}
+
+func (s *BadgerStore) set(key []byte, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(key, data)
+	})
+}
+
+func (s *BadgerStore) get(key []byte, v any) error {
+	return s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, v)
+		})
+	})
+}
+
+//
+// ============================
+// INSERT METHODS
+// ============================
+//
+
+func (s *BadgerStore) InsertUser(wallet string) {
+	var existing userRecord
+	if err := s.get(userKey(wallet), &existing); err == nil {
+		return
+	}
+	_ = s.set(userKey(wallet), userRecord{CreatedAt: time.Now().UTC()})
+}
+
+func (s *BadgerStore) InsertDataSet(datasetID, wallet string) {
+	var existing datasetRecord
+	if err := s.get(datasetKey(datasetID), &existing); err == nil {
+		return
+	}
+	_ = s.set(datasetKey(datasetID), datasetRecord{Wallet: wallet, CreatedAt: time.Now().UTC()})
+}
+
+func (s *BadgerStore) InsertObject(objectID, wallet, datasetID string, size int64) {
+	_ = s.set(objectKey(wallet, objectID), objectRecord{
+		Wallet:    wallet,
+		DataSetID: datasetID,
+		Size:      size,
+		CreatedAt: time.Now().UTC(),
+	})
+	s.writeUsageJournal(wallet, datasetID, usageEventCreated, size)
+}
+
+// InsertLease denormalizes the object's size onto the lease record (there is
+// no JOIN in Badger to recover it at read time the way SQLiteStore does) and
+// refreshes every pointer index the lease participates in.
+func (s *BadgerStore) InsertLease(l ObjectLease) {
+	var obj objectRecord
+	if err := s.get(objectKey(l.Wallet, l.ObjectID), &obj); err == nil {
+		l.Size = obj.Size
+	}
+
+	primary := leasePrimaryKey(l.ExpireAt, l.LeaseID)
+
+	_ = s.db.Update(func(txn *badger.Txn) error {
+		data, err := json.Marshal(l)
+		if err != nil {
+			return err
+		}
+		if err := txn.Set(primary, data); err != nil {
+			return err
+		}
+		if err := txn.Set(leaseIDKey(l.LeaseID), primary); err != nil {
+			return err
+		}
+		if l.Bucket != "" || l.Key != "" {
+			if err := txn.Set(leaseBucketKey(l.Bucket, l.Key), []byte(l.LeaseID)); err != nil {
+				return err
+			}
+		}
+		if err := txn.Set(leaseObjKey(l.ObjectID), []byte(l.LeaseID)); err != nil {
+			return err
+		}
+		return txn.Set(leaseWalletKey(l.Wallet, l.CreatedAt, l.LeaseID), []byte(l.LeaseID))
+	})
+}
+
+//
+// ============================
+// LEASE QUERIES
+// ============================
+//
+
+func (s *BadgerStore) getLeaseByPrimaryKey(primary []byte) (*ObjectLease, error) {
+	var lease ObjectLease
+	if err := s.get(primary, &lease); err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+func (s *BadgerStore) resolveLeaseID(leaseID string) (*ObjectLease, error) {
+	var primary []byte
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(leaseIDKey(leaseID))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			primary = append([]byte(nil), val...)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.getLeaseByPrimaryKey(primary)
+}
+
+func (s *BadgerStore) GetLeaseByID(leaseID string) (*ObjectLease, error) {
+	return s.resolveLeaseID(leaseID)
+}
+
+func (s *BadgerStore) GetActiveLeaseByObjectID(objectID string) (*ObjectLease, error) {
+	leaseID, err := s.readPointer(leaseObjKey(objectID))
+	if err != nil {
+		return nil, err
+	}
+	return s.resolveLeaseID(leaseID)
+}
+
+func (s *BadgerStore) GetActiveLeaseByBucketKey(bucket, key string) (*ObjectLease, error) {
+	leaseID, err := s.readPointer(leaseBucketKey(bucket, key))
+	if err != nil {
+		return nil, err
+	}
+	return s.resolveLeaseID(leaseID)
+}
+
+func (s *BadgerStore) readPointer(key []byte) (string, error) {
+	var out string
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			out = string(val)
+			return nil
+		})
+	})
+	return out, err
+}
+
+// ListObjectsByBucket iterates the leasebucket/<bucket>/ prefix in key
+// (hence object-key) order, the same ordering ListObjectsV2 needs, applying
+// prefix/startAfter/maxKeys and the tombstoned/deleted visibility rules
+// inline since there is no WHERE clause to push them into.
+func (s *BadgerStore) ListObjectsByBucket(bucket, prefix, startAfter string, maxKeys int) ([]ObjectLease, error) {
+	base := []byte("leasebucket/" + bucket + "/")
+	scanPrefix := []byte(string(base) + prefix)
+
+	var leases []ObjectLease
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = scanPrefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(scanPrefix); it.ValidForPrefix(scanPrefix) && len(leases) < maxKeys; it.Next() {
+			key := string(it.Item().Key()[len(base):])
+			if startAfter != "" && key <= startAfter {
+				continue
+			}
+
+			var leaseID string
+			if err := it.Item().Value(func(val []byte) error {
+				leaseID = string(val)
+				return nil
+			}); err != nil {
+				return err
+			}
+
+			lease, err := s.resolveLeaseID(leaseID)
+			if err != nil {
+				continue
+			}
+			if lease.DeletedAt != nil || lease.TombstonedAt != nil {
+				continue
+			}
+			leases = append(leases, *lease)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return leases, nil
+}
+
+// ListObjectsByWallet walks leasewallet/<wallet>/ in key order, which is
+// newest-first because leaseWalletKey inverts the timestamp.
+func (s *BadgerStore) ListObjectsByWallet(wallet string) ([]ObjectLease, error) {
+	prefix := []byte("leasewallet/" + wallet + "/")
+
+	var leases []ObjectLease
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var leaseID string
+			if err := it.Item().Value(func(val []byte) error {
+				leaseID = string(val)
+				return nil
+			}); err != nil {
+				return err
+			}
+			lease, err := s.resolveLeaseID(leaseID)
+			if err != nil {
+				continue
+			}
+			leases = append(leases, *lease)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return leases, nil
+}
+
+// TombstoneLease mirrors SQLiteStore's: idempotent, and it writes the usage
+// journal entry once, at the moment of the transition into tombstoned.
+func (s *BadgerStore) TombstoneLease(leaseID string) error {
+	lease, err := s.resolveLeaseID(leaseID)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if lease.TombstonedAt != nil {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	lease.TombstonedAt = &now
+
+	if err := s.set(leasePrimaryKey(lease.ExpireAt, lease.LeaseID), lease); err != nil {
+		return err
+	}
+	s.writeUsageJournal(lease.Wallet, lease.StorageRef.DataSetID, usageEventExpired, lease.Size)
+	return nil
+}
+
+// MarkDeleted mirrors SQLiteStore's: idempotent, and it records the usage
+// journal's expired->deleted entry once, after deleted_at is written.
+func (s *BadgerStore) MarkDeleted(leaseID string) error {
+	lease, err := s.resolveLeaseID(leaseID)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if lease.DeletedAt != nil {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	lease.DeletedAt = &now
+
+	if err := s.set(leasePrimaryKey(lease.ExpireAt, lease.LeaseID), lease); err != nil {
+		return err
+	}
+	s.writeUsageJournal(lease.Wallet, lease.StorageRef.DataSetID, usageEventDeleted, lease.Size)
+	return nil
+}
+
+// GetActiveLeaseExpirations walks the whole lease/ prefix, in expiry order,
+// to seed the expiration worker's heap at startup. Equivalent to
+// SQLiteStore's "WHERE deleted_at IS NULL AND tombstoned_at IS NULL" scan.
+func (s *BadgerStore) GetActiveLeaseExpirations() ([]leaseExpiration, error) {
+	prefix := []byte("lease/")
+
+	var expirations []leaseExpiration
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var lease ObjectLease
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &lease)
+			}); err != nil {
+				return err
+			}
+			if lease.DeletedAt != nil || lease.TombstonedAt != nil {
+				continue
+			}
+			expirations = append(expirations, leaseExpiration{LeaseID: lease.LeaseID, ExpireAt: lease.ExpireAt})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expirations, nil
+}
+
+//
+// ============================
+// CREDENTIALS
+// ============================
+//
+
+// GetOrCreateCredential is the BadgerDB twin of SQLiteStore's method of the
+// same name: it only ever returns a secret on the wallet's first call, and
+// ErrCredentialAlreadyIssued on every call after that.
+func (s *BadgerStore) GetOrCreateCredential(wallet string) (string, error) {
+	s.InsertUser(wallet)
+
+	var rec userRecord
+	if err := s.get(userKey(wallet), &rec); err != nil {
+		return "", err
+	}
+	if rec.SecretKey != "" {
+		return "", ErrCredentialAlreadyIssued
+	}
+
+	generated, err := randomSecretKey()
+	if err != nil {
+		return "", err
+	}
+	rec.SecretKey = generated
+	if err := s.set(userKey(wallet), rec); err != nil {
+		return "", err
+	}
+	return generated, nil
+}
+
+// GetWalletForAccessKey looks up by wallet directly, same as SQLiteStore:
+// access key id and wallet are the same string today.
+func (s *BadgerStore) GetWalletForAccessKey(accessKeyID string) (wallet, secretKey string, err error) {
+	var rec userRecord
+	if err := s.get(userKey(accessKeyID), &rec); err != nil {
+		return "", "", err
+	}
+	return accessKeyID, rec.SecretKey, nil
+}
+
+//
+// ============================
+// MULTIPART UPLOADS
+// ============================
+//
+
+func mpuKey(uploadID string) []byte { return []byte("mpu/" + uploadID) }
+func mpPartKey(uploadID string, partNumber int) []byte {
+	return []byte(fmt.Sprintf("mpupart/%s/%08d", uploadID, partNumber))
+}
+func mpPartPrefix(uploadID string) []byte { return []byte("mpupart/" + uploadID + "/") }
+
+func (s *BadgerStore) InsertMultipartUpload(u MultipartUpload) {
+	_ = s.set(mpuKey(u.UploadID), u)
+}
+
+func (s *BadgerStore) GetMultipartUpload(uploadID string) (*MultipartUpload, error) {
+	var u MultipartUpload
+	if err := s.get(mpuKey(uploadID), &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (s *BadgerStore) UpsertMultipartPart(p MultipartPart) {
+	_ = s.set(mpPartKey(p.UploadID, p.PartNumber), p)
+}
+
+func (s *BadgerStore) ListMultipartParts(uploadID string) ([]MultipartPart, error) {
+	prefix := mpPartPrefix(uploadID)
+
+	var parts []MultipartPart
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var p MultipartPart
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &p)
+			}); err != nil {
+				return err
+			}
+			parts = append(parts, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parts, nil
+}
+
+// CompleteMultipartUpload and AbortMultipartUpload both just drop the mpu
+// key: SQLiteStore instead sets completed_at/aborted_at and filters on them
+// in GetMultipartUpload, but since nothing else here ever looks an upload up
+// by id after it's done, deleting has the same observable effect with one
+// less pointer to keep consistent.
+func (s *BadgerStore) CompleteMultipartUpload(uploadID string) {
+	_ = s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(mpuKey(uploadID))
+	})
+}
+
+func (s *BadgerStore) AbortMultipartUpload(uploadID string) {
+	_ = s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(mpuKey(uploadID))
+	})
+}
+
+//
+// ============================
+// USAGE JOURNAL / CACHE
+// ============================
+//
+
+const usageJournalSeqKey = "usagejournalseq"
+const usageCursorKey = "usagecursor"
+
+func usageJournalKey(id int64) []byte {
+	return []byte(fmt.Sprintf("usagejournal/%020d", id))
+}
+func usageCacheKey(wallet, datasetID string) []byte {
+	return []byte("usagecache/" + wallet + "/" + datasetID)
+}
+func usageDailyKey(wallet, datasetID, day string) []byte {
+	return []byte("usagedaily/" + wallet + "/" + datasetID + "/" + day)
+}
+
+func (s *BadgerStore) nextUsageJournalID() (int64, error) {
+	var next int64
+	err := s.db.Update(func(txn *badger.Txn) error {
+		var current int64
+		item, err := txn.Get([]byte(usageJournalSeqKey))
+		if err == nil {
+			_ = item.Value(func(val []byte) error {
+				current = int64(binary.BigEndian.Uint64(val))
+				return nil
+			})
+		} else if !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+
+		next = current + 1
+		buf := make([]byte, 8)
+		binary.BigEndian.PutUint64(buf, uint64(next))
+		return txn.Set([]byte(usageJournalSeqKey), buf)
+	})
+	return next, err
+}
+
+func (s *BadgerStore) writeUsageJournal(wallet, datasetID, eventType string, size int64) {
+	id, err := s.nextUsageJournalID()
+	if err != nil {
+		return
+	}
+	now := time.Now().UTC()
+	_ = s.set(usageJournalKey(id), usageJournalEntry{
+		ID:        id,
+		Wallet:    wallet,
+		DataSetID: datasetID,
+		EventType: eventType,
+		SizeBytes: size,
+		Day:       now.Format("2006-01-02"),
+	})
+}
+
+func (s *BadgerStore) GetUsageCursor() (int64, error) {
+	var cursor int64
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(usageCursorKey))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			cursor = int64(binary.BigEndian.Uint64(val))
+			return nil
+		})
+	})
+	return cursor, err
+}
+
+func (s *BadgerStore) SetUsageCursor(cursor int64) error {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(cursor))
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(usageCursorKey), buf)
+	})
+}
+
+// FetchUsageJournalBatch walks usagejournal/ in id order starting just past
+// afterID, the same bounded-chunk contract SQLiteStore's version has.
+func (s *BadgerStore) FetchUsageJournalBatch(afterID int64, limit int) ([]usageJournalEntry, error) {
+	prefix := []byte("usagejournal/")
+	seek := usageJournalKey(afterID + 1)
+
+	var entries []usageJournalEntry
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(seek); it.ValidForPrefix(prefix) && len(entries) < limit; it.Next() {
+			var e usageJournalEntry
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &e)
+			}); err != nil {
+				return err
+			}
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ApplyUsageDelta folds one journal entry into usagecache/<wallet>/<dataset>
+// (and usagedaily/... for creates), the same state machine ApplyUsageDelta
+// implements against data_usage_cache in SQLiteStore.
+func (s *BadgerStore) ApplyUsageDelta(entry usageJournalEntry) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		key := usageCacheKey(entry.Wallet, entry.DataSetID)
+
+		var cache DatasetUsage
+		cache.DataSetID = entry.DataSetID
+		if item, err := txn.Get(key); err == nil {
+			_ = item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &cache)
+			})
+		} else if !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+
+		switch entry.EventType {
+		case usageEventCreated:
+			cache.ActiveBytes += entry.SizeBytes
+			cache.ActiveCount++
+		case usageEventExpired:
+			cache.ActiveBytes -= entry.SizeBytes
+			cache.ExpiredBytes += entry.SizeBytes
+			cache.ActiveCount--
+			cache.ExpiredCount++
+		case usageEventDeleted:
+			cache.ExpiredBytes -= entry.SizeBytes
+			cache.DeletedBytes += entry.SizeBytes
+			cache.ExpiredCount--
+			cache.DeletedCount++
+		}
+
+		data, err := json.Marshal(cache)
+		if err != nil {
+			return err
+		}
+		if err := txn.Set(key, data); err != nil {
+			return err
+		}
+
+		if entry.EventType != usageEventCreated {
+			return nil
+		}
+
+		dailyKey := usageDailyKey(entry.Wallet, entry.DataSetID, entry.Day)
+		var newBytes int64
+		if item, err := txn.Get(dailyKey); err == nil {
+			_ = item.Value(func(val []byte) error {
+				newBytes = int64(binary.BigEndian.Uint64(val))
+				return nil
+			})
+		} else if !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+		newBytes += entry.SizeBytes
+
+		buf := make([]byte, 8)
+		binary.BigEndian.PutUint64(buf, uint64(newBytes))
+		return txn.Set(dailyKey, buf)
+	})
+}
+
+// GetUsageBreakdown scans usagecache/<wallet>/ and usagedaily/<wallet>/ for
+// one wallet's datasets and its last-30-days histogram.
+func (s *BadgerStore) GetUsageBreakdown(wallet string) (*UsageBreakdown, error) {
+	breakdown := &UsageBreakdown{Wallet: wallet}
+
+	cachePrefix := []byte("usagecache/" + wallet + "/")
+	dailyPrefix := []byte("usagedaily/" + wallet + "/")
+	since := time.Now().UTC().AddDate(0, 0, -30).Format("2006-01-02")
+
+	dailyTotals := map[string]int64{}
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		co := badger.DefaultIteratorOptions
+		co.Prefix = cachePrefix
+		cit := txn.NewIterator(co)
+		defer cit.Close()
+		for cit.Seek(cachePrefix); cit.ValidForPrefix(cachePrefix); cit.Next() {
+			var d DatasetUsage
+			if err := cit.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &d)
+			}); err != nil {
+				return err
+			}
+			breakdown.Datasets = append(breakdown.Datasets, d)
+		}
+
+		do := badger.DefaultIteratorOptions
+		do.Prefix = dailyPrefix
+		dit := txn.NewIterator(do)
+		defer dit.Close()
+		for dit.Seek(dailyPrefix); dit.ValidForPrefix(dailyPrefix); dit.Next() {
+			keyParts := string(dit.Item().Key())
+			day := keyParts[len(keyParts)-len("2006-01-02"):]
+			if day < since {
+				continue
+			}
+			var newBytes int64
+			if err := dit.Item().Value(func(val []byte) error {
+				newBytes = int64(binary.BigEndian.Uint64(val))
+				return nil
+			}); err != nil {
+				return err
+			}
+			dailyTotals[day] += newBytes
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for day, total := range dailyTotals {
+		breakdown.Daily = append(breakdown.Daily, DailyUsage{Day: day, NewBytes: total})
+	}
+	return breakdown, nil
+}
+
+//
+// ============================
+// DASHBOARD
+// ============================
+//
+
+func (s *BadgerStore) GetDashboardStats() (*DashboardStats, error) {
+	ext, err := s.GetExtendedStats()
+	if err != nil {
+		return nil, err
+	}
+	return &DashboardStats{
+		TotalUsers:        ext.TotalUsers,
+		NewUsersToday:     ext.NewUsersToday,
+		TotalStorageBytes: ext.TotalStorageBytes,
+		StorageTodayBytes: ext.StorageTodayBytes,
+		ExpiringIn7Days:   ext.ExpiringIn7Days,
+	}, nil
+}
+
+// GetExtendedStats sums usagecache/ and usagedaily/ the same way
+// SQLiteStore sums data_usage_cache and data_usage_daily, and walks the
+// lease/ prefix for the expiring-soon count since that's still a live range
+// check over expire_at, not a rolling total the crawler maintains.
+func (s *BadgerStore) GetExtendedStats() (*ExtendedStats, error) {
+	now := time.Now().UTC()
+	today := now.Truncate(24 * time.Hour).Format("2006-01-02")
+	in7days := now.Add(7 * 24 * time.Hour)
+
+	stats := &ExtendedStats{}
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		uo := badger.DefaultIteratorOptions
+		uo.Prefix = []byte("user/")
+		uit := txn.NewIterator(uo)
+		defer uit.Close()
+		for uit.Seek(uo.Prefix); uit.ValidForPrefix(uo.Prefix); uit.Next() {
+			stats.TotalUsers++
+			var rec userRecord
+			if err := uit.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &rec)
+			}); err != nil {
+				return err
+			}
+			if !rec.CreatedAt.Before(now.Truncate(24 * time.Hour)) {
+				stats.NewUsersToday++
+			}
+		}
+
+		co := badger.DefaultIteratorOptions
+		co.Prefix = []byte("usagecache/")
+		cit := txn.NewIterator(co)
+		defer cit.Close()
+		for cit.Seek(co.Prefix); cit.ValidForPrefix(co.Prefix); cit.Next() {
+			var d DatasetUsage
+			if err := cit.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &d)
+			}); err != nil {
+				return err
+			}
+			stats.TotalStorageBytes += d.ActiveBytes + d.ExpiredBytes + d.DeletedBytes
+			stats.ActiveObjects += d.ActiveCount
+			stats.ExpiredObjects += d.ExpiredCount
+			stats.DeletedObjects += d.DeletedCount
+		}
+
+		do := badger.DefaultIteratorOptions
+		do.Prefix = []byte("usagedaily/")
+		dit := txn.NewIterator(do)
+		defer dit.Close()
+		for dit.Seek(do.Prefix); dit.ValidForPrefix(do.Prefix); dit.Next() {
+			key := string(dit.Item().Key())
+			if key[len(key)-len(today):] != today {
+				continue
+			}
+			if err := dit.Item().Value(func(val []byte) error {
+				stats.StorageTodayBytes += int64(binary.BigEndian.Uint64(val))
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+
+		lo := badger.DefaultIteratorOptions
+		lo.Prefix = []byte("lease/")
+		lit := txn.NewIterator(lo)
+		defer lit.Close()
+		for lit.Seek(lo.Prefix); lit.ValidForPrefix(lo.Prefix); lit.Next() {
+			var lease ObjectLease
+			if err := lit.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &lease)
+			}); err != nil {
+				return err
+			}
+			if lease.DeletedAt != nil || lease.TombstonedAt != nil {
+				continue
+			}
+			if !lease.ExpireAt.Before(now) && !lease.ExpireAt.After(in7days) {
+				stats.ExpiringIn7Days++
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+//
+// ============================
+// UPLOAD WRITE-AHEAD LOG
+// ============================
+//
+
+func pendingUploadKey(id string) []byte { return []byte("pending/" + id) }
+
+func (s *BadgerStore) RecordUploadIntent(p PendingUpload) error {
+	return s.set(pendingUploadKey(p.ID), p)
+}
+
+func (s *BadgerStore) SetUploadIntentPieceCID(id, pieceCID string) error {
+	var p PendingUpload
+	if err := s.get(pendingUploadKey(id), &p); err != nil {
+		return err
+	}
+	p.PieceCID = pieceCID
+	return s.set(pendingUploadKey(id), p)
+}
+
+func (s *BadgerStore) DropUploadIntent(id string) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(pendingUploadKey(id))
+	})
+}
+
+func (s *BadgerStore) ListPendingUploads() ([]PendingUpload, error) {
+	prefix := []byte("pending/")
+
+	var pending []PendingUpload
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var p PendingUpload
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &p)
+			}); err != nil {
+				return err
+			}
+			pending = append(pending, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// CommitUpload writes the user/dataset/object/lease records and drops the
+// pending_uploads-equivalent journal entry inside one Badger transaction,
+// the same all-or-nothing guarantee SQLiteStore's version gets from a SQL
+// transaction. It doesn't call InsertUser/InsertDataSet/InsertObject/
+// InsertLease directly since those each open their own transaction; the
+// logic is duplicated here so every write lands in a single one.
+func (s *BadgerStore) CommitUpload(p PendingUpload) error {
+	lease := ObjectLease{
+		LeaseID:   p.LeaseID,
+		ObjectID:  p.ObjectID,
+		Bucket:    p.Bucket,
+		Key:       p.Key,
+		Wallet:    p.Wallet,
+		CreatedAt: p.CreatedAt,
+		ExpireAt:  p.ExpireAt,
+		StorageRef: StorageRef{
+			DataSetID: p.DataSetID,
+			PieceCID:  p.PieceCID,
+		},
+		Size: p.Size,
+	}
+	primary := leasePrimaryKey(lease.ExpireAt, lease.LeaseID)
+
+	err := s.db.Update(func(txn *badger.Txn) error {
+		if _, err := txn.Get(userKey(p.Wallet)); err != nil {
+			data, merr := json.Marshal(userRecord{CreatedAt: p.CreatedAt})
+			if merr != nil {
+				return merr
+			}
+			if err := txn.Set(userKey(p.Wallet), data); err != nil {
+				return err
+			}
+		}
+
+		if _, err := txn.Get(datasetKey(p.DataSetID)); err != nil {
+			data, merr := json.Marshal(datasetRecord{Wallet: p.Wallet, CreatedAt: p.CreatedAt})
+			if merr != nil {
+				return merr
+			}
+			if err := txn.Set(datasetKey(p.DataSetID), data); err != nil {
+				return err
+			}
+		}
+
+		objData, err := json.Marshal(objectRecord{Wallet: p.Wallet, DataSetID: p.DataSetID, Size: p.Size, CreatedAt: p.CreatedAt})
+		if err != nil {
+			return err
+		}
+		if err := txn.Set(objectKey(p.Wallet, p.ObjectID), objData); err != nil {
+			return err
+		}
+
+		leaseData, err := json.Marshal(lease)
+		if err != nil {
+			return err
+		}
+		if err := txn.Set(primary, leaseData); err != nil {
+			return err
+		}
+		if err := txn.Set(leaseIDKey(lease.LeaseID), primary); err != nil {
+			return err
+		}
+		if lease.Bucket != "" || lease.Key != "" {
+			if err := txn.Set(leaseBucketKey(lease.Bucket, lease.Key), []byte(lease.LeaseID)); err != nil {
+				return err
+			}
+		}
+		if err := txn.Set(leaseObjKey(lease.ObjectID), []byte(lease.LeaseID)); err != nil {
+			return err
+		}
+		if err := txn.Set(leaseWalletKey(lease.Wallet, lease.CreatedAt, lease.LeaseID), []byte(lease.LeaseID)); err != nil {
+			return err
+		}
+
+		return txn.Delete(pendingUploadKey(p.ID))
+	})
+	if err != nil {
+		return err
+	}
+
+	s.writeUsageJournal(p.Wallet, p.DataSetID, usageEventCreated, p.Size)
+	return nil
+}
+
+//
+// ============================
+// BUCKET OWNERSHIP
+// ============================
+//
+
+func bucketOwnerKey(bucket string) []byte { return []byte("bucketowner/" + bucket) }
+
+// EnsureBucketOwnership is the BadgerDB twin of SQLiteStore's method of the
+// same name: first-writer-wins, done inside a single transaction so a
+// concurrent claim by two different wallets can't both succeed.
+func (s *BadgerStore) EnsureBucketOwnership(bucket, wallet string) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		item, err := txn.Get(bucketOwnerKey(bucket))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return txn.Set(bucketOwnerKey(bucket), []byte(wallet))
+		}
+		if err != nil {
+			return err
+		}
+
+		var owner string
+		if err := item.Value(func(val []byte) error {
+			owner = string(val)
+			return nil
+		}); err != nil {
+			return err
+		}
+		if owner != wallet {
+			return ErrBucketOwnedByOther
+		}
+		return nil
+	})
+}
+
+// BucketOwner is the BadgerDB twin of SQLiteStore's method of the same name.
+func (s *BadgerStore) BucketOwner(bucket string) (wallet string, ok bool, err error) {
+	err = s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(bucketOwnerKey(bucket))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			wallet = string(val)
+			ok = true
+			return nil
+		})
+	})
+	return wallet, ok, err
+}
@@ -0,0 +1,48 @@
+package lifecycle
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrBucketOwnedByOther is returned by EnsureBucketOwnership when a bucket
+// was already claimed by a different wallet.
+var ErrBucketOwnedByOther = errors.New("bucket is owned by a different wallet")
+
+// EnsureBucketOwnership claims bucket for wallet the first time it is seen
+// (first-writer-wins), or confirms wallet already owns it. It is called on
+// every write path (PUT, CreateMultipartUpload) so a bucket can never end up
+// with objects from more than one wallet.
+func (s *SQLiteStore) EnsureBucketOwnership(bucket, wallet string) error {
+	if _, err := s.db.Exec(
+		`INSERT OR IGNORE INTO buckets(bucket, wallet, created_at) VALUES (?, ?, ?)`,
+		bucket, wallet, iso(time.Now()),
+	); err != nil {
+		return err
+	}
+
+	var owner string
+	if err := s.db.QueryRow(`SELECT wallet FROM buckets WHERE bucket = ?`, bucket).Scan(&owner); err != nil {
+		return err
+	}
+	if owner != wallet {
+		return ErrBucketOwnedByOther
+	}
+	return nil
+}
+
+// BucketOwner looks up the wallet that owns bucket, if any. Read paths
+// (GET/HEAD/DELETE/List) use this instead of EnsureBucketOwnership so that
+// listing or fetching from a bucket nobody has written to yet doesn't claim
+// it; ok is false when the bucket has never been claimed.
+func (s *SQLiteStore) BucketOwner(bucket string) (wallet string, ok bool, err error) {
+	err = s.db.QueryRow(`SELECT wallet FROM buckets WHERE bucket = ?`, bucket).Scan(&wallet)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return wallet, true, nil
+}
@@ -0,0 +1,207 @@
+package lifecycle
+
+import (
+	"database/sql"
+	"time"
+)
+
+// GetActiveLeaseByBucketKey resolves the current lease for an S3-style
+// bucket/key pair, the same way GetActiveLeaseByObjectID resolves one by
+// object id. Only the most recent lease for the pair is considered, since a
+// bucket/key can be overwritten by a later PUT.
+func (s *SQLiteStore) GetActiveLeaseByBucketKey(bucket, key string) (*ObjectLease, error) {
+	row := s.db.QueryRow(`
+SELECT lease_id, object_id, bucket, object_key, wallet, created_at, expire_at, deleted_at, tombstoned_at, dataset_id, piece_cid
+FROM leases
+WHERE bucket = ? AND object_key = ?
+ORDER BY created_at DESC
+LIMIT 1
+`, bucket, key)
+
+	return scanLease(row)
+}
+
+func scanLease(row *sql.Row) (*ObjectLease, error) {
+	var lease ObjectLease
+	var createdStr, expireStr string
+	var deletedStr, tombstonedStr sql.NullString
+	var datasetID, pieceCID string
+
+	err := row.Scan(
+		&lease.LeaseID,
+		&lease.ObjectID,
+		&lease.Bucket,
+		&lease.Key,
+		&lease.Wallet,
+		&createdStr,
+		&expireStr,
+		&deletedStr,
+		&tombstonedStr,
+		&datasetID,
+		&pieceCID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	lease.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdStr)
+	lease.ExpireAt, _ = time.Parse(time.RFC3339Nano, expireStr)
+
+	if deletedStr.Valid {
+		t, _ := time.Parse(time.RFC3339Nano, deletedStr.String)
+		lease.DeletedAt = &t
+	}
+	if tombstonedStr.Valid {
+		t, _ := time.Parse(time.RFC3339Nano, tombstonedStr.String)
+		lease.TombstonedAt = &t
+	}
+
+	lease.StorageRef = StorageRef{
+		DataSetID: datasetID,
+		PieceCID:  pieceCID,
+	}
+
+	return &lease, nil
+}
+
+// TombstoneLease marks a lease as immediately invisible to GET/LIST without
+// performing the physical delete yet. This lets a DELETE request return
+// quickly while the adapter cleanup happens out of band. It is idempotent:
+// tombstoning an already-tombstoned lease is a no-op.
+func (s *SQLiteStore) TombstoneLease(leaseID string) error {
+	var wallet, datasetID string
+	var size int64
+	err := s.db.QueryRow(`
+SELECT l.wallet, l.dataset_id, o.size_bytes
+FROM leases l
+JOIN objects o ON o.object_id = l.object_id
+WHERE l.lease_id = ? AND l.tombstoned_at IS NULL
+`, leaseID).Scan(&wallet, &datasetID, &size)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.db.Exec(
+		`UPDATE leases SET tombstoned_at = ? WHERE lease_id = ?`,
+		iso(time.Now()),
+		leaseID,
+	); err != nil {
+		return err
+	}
+
+	s.writeUsageJournal(wallet, datasetID, usageEventExpired, size)
+	return nil
+}
+
+// ListObjectsByBucket returns leases for a bucket whose key has the given
+// prefix, ordered lexicographically by key, for ListObjectsV2-style paging.
+// Tombstoned and deleted leases are excluded, same as a live GET would be.
+func (s *SQLiteStore) ListObjectsByBucket(bucket, prefix, startAfter string, maxKeys int) ([]ObjectLease, error) {
+	rows, err := s.db.Query(`
+SELECT l.lease_id, l.object_id, l.bucket, l.object_key, l.wallet, l.created_at, l.expire_at, l.deleted_at, l.tombstoned_at, l.dataset_id, l.piece_cid, o.size_bytes
+FROM leases l
+JOIN objects o ON o.object_id = l.object_id
+WHERE l.bucket = ?
+  AND l.object_key LIKE ? ESCAPE '\'
+  AND l.object_key > ?
+  AND l.deleted_at IS NULL
+  AND l.tombstoned_at IS NULL
+ORDER BY l.object_key ASC
+LIMIT ?
+`, bucket, likePrefix(prefix), startAfter, maxKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leases []ObjectLease
+	for rows.Next() {
+		var lease ObjectLease
+		var createdStr, expireStr string
+		var deletedStr, tombstonedStr sql.NullString
+		var datasetID, pieceCID string
+		var size int64
+
+		if err := rows.Scan(
+			&lease.LeaseID, &lease.ObjectID, &lease.Bucket, &lease.Key, &lease.Wallet,
+			&createdStr, &expireStr, &deletedStr, &tombstonedStr, &datasetID, &pieceCID, &size,
+		); err != nil {
+			return nil, err
+		}
+
+		lease.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdStr)
+		lease.ExpireAt, _ = time.Parse(time.RFC3339Nano, expireStr)
+		lease.StorageRef = StorageRef{DataSetID: datasetID, PieceCID: pieceCID}
+		lease.Size = size
+
+		leases = append(leases, lease)
+	}
+
+	return leases, rows.Err()
+}
+
+// ListObjectsByWallet returns every lease owned by a wallet, newest first.
+// This backs the legacy GET /objects route, which used to run its own ad
+// hoc join straight against *sql.DB; going through MetaStore instead means
+// that route works the same way against any backend.
+func (s *SQLiteStore) ListObjectsByWallet(wallet string) ([]ObjectLease, error) {
+	rows, err := s.db.Query(`
+SELECT o.object_id, o.size_bytes, l.created_at, l.expire_at, l.deleted_at, l.tombstoned_at
+FROM objects o
+JOIN leases l ON o.object_id = l.object_id
+WHERE o.wallet = ?
+ORDER BY l.created_at DESC
+`, wallet)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leases []ObjectLease
+	for rows.Next() {
+		var lease ObjectLease
+		var createdStr, expireStr string
+		var deletedStr, tombstonedStr sql.NullString
+		var size int64
+
+		if err := rows.Scan(&lease.ObjectID, &size, &createdStr, &expireStr, &deletedStr, &tombstonedStr); err != nil {
+			return nil, err
+		}
+
+		lease.Wallet = wallet
+		lease.Size = size
+		lease.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdStr)
+		lease.ExpireAt, _ = time.Parse(time.RFC3339Nano, expireStr)
+
+		if deletedStr.Valid {
+			t, _ := time.Parse(time.RFC3339Nano, deletedStr.String)
+			lease.DeletedAt = &t
+		}
+		if tombstonedStr.Valid {
+			t, _ := time.Parse(time.RFC3339Nano, tombstonedStr.String)
+			lease.TombstonedAt = &t
+		}
+
+		leases = append(leases, lease)
+	}
+
+	return leases, rows.Err()
+}
+
+// likePrefix escapes SQL LIKE metacharacters in a user-supplied key prefix
+// so ListObjectsByBucket's "prefix%" match doesn't treat "_" or "%" in a key
+// as wildcards.
+func likePrefix(prefix string) string {
+	escaped := make([]byte, 0, len(prefix)+1)
+	for i := 0; i < len(prefix); i++ {
+		switch prefix[i] {
+		case '\\', '%', '_':
+			escaped = append(escaped, '\\')
+		}
+		escaped = append(escaped, prefix[i])
+	}
+	return string(escaped) + "%"
+}
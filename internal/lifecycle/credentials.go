@@ -0,0 +1,59 @@
+package lifecycle
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+)
+
+// ErrCredentialAlreadyIssued is returned by GetOrCreateCredential when a
+// wallet already has a secret key on file. Qave has no out-of-band wallet
+// authentication of its own, so a secret can only ever be handed out once,
+// at mint time: anyone who could re-fetch an existing wallet's secret just
+// by naming that wallet would be able to forge its SigV4 signatures.
+var ErrCredentialAlreadyIssued = errors.New("credential already issued for this wallet")
+
+// GetOrCreateCredential mints and persists a SigV4 secret key the first time
+// a wallet is seen, and returns ErrCredentialAlreadyIssued on every call
+// after that — it never reveals a previously issued secret again. The
+// wallet address itself is used as the access key id, so S3 clients
+// authenticate with AccessKeyID=<wallet>, SecretAccessKey=<this>.
+func (s *SQLiteStore) GetOrCreateCredential(wallet string) (string, error) {
+	s.InsertUser(wallet)
+
+	var secret sql.NullString
+	if err := s.db.QueryRow(`SELECT secret_key FROM users WHERE wallet = ?`, wallet).Scan(&secret); err != nil {
+		return "", err
+	}
+	if secret.Valid && secret.String != "" {
+		return "", ErrCredentialAlreadyIssued
+	}
+
+	generated, err := randomSecretKey()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := s.db.Exec(`UPDATE users SET secret_key = ? WHERE wallet = ?`, generated, wallet); err != nil {
+		return "", err
+	}
+
+	return generated, nil
+}
+
+// GetWalletForAccessKey looks up the wallet behind a SigV4 access key id.
+// Access key id and wallet are the same string today, but keeping this as
+// its own lookup means that can change without touching callers.
+func (s *SQLiteStore) GetWalletForAccessKey(accessKeyID string) (wallet, secretKey string, err error) {
+	err = s.db.QueryRow(`SELECT wallet, secret_key FROM users WHERE wallet = ?`, accessKeyID).Scan(&wallet, &secretKey)
+	return wallet, secretKey, err
+}
+
+func randomSecretKey() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
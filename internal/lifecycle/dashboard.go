@@ -10,6 +10,11 @@ type DashboardStats struct {
 	ExpiringIn7Days   int64
 }
 
+// GetDashboardStats used to SUM(size_bytes) and scan the whole objects
+// table on every call. The storage totals now come only from the small
+// data_usage_cache and data_usage_daily tables, which the usage crawler
+// keeps up to date incrementally; the user counts and the expiring-soon
+// count below still query users and leases directly.
 func (s *SQLiteStore) GetDashboardStats() (*DashboardStats, error) {
 	now := time.Now().UTC()
 	today := now.Truncate(24 * time.Hour)
@@ -17,33 +22,30 @@ func (s *SQLiteStore) GetDashboardStats() (*DashboardStats, error) {
 
 	stats := &DashboardStats{}
 
-	// total users
 	_ = s.db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&stats.TotalUsers)
 
-	// new users today
 	_ = s.db.QueryRow(
 		`SELECT COUNT(*) FROM users WHERE created_at >= ?`,
 		iso(today),
 	).Scan(&stats.NewUsersToday)
 
-	// total storage
 	_ = s.db.QueryRow(
-		`SELECT COALESCE(SUM(size_bytes),0) FROM objects`,
+		`SELECT COALESCE(SUM(active_bytes + expired_bytes + deleted_bytes),0) FROM data_usage_cache`,
 	).Scan(&stats.TotalStorageBytes)
 
-	// today storage
 	_ = s.db.QueryRow(
-		`SELECT COALESCE(SUM(size_bytes),0) FROM objects WHERE created_at >= ?`,
-		iso(today),
+		`SELECT COALESCE(SUM(new_bytes),0) FROM data_usage_daily WHERE day = ?`,
+		today.Format("2006-01-02"),
 	).Scan(&stats.StorageTodayBytes)
 
-	// expiring in 7 days
+	// Still a direct lease scan: "how many expire soon" isn't a rolling
+	// total the crawler can maintain the same way, and the leases table is
+	// the source of truth for expire_at.
 	_ = s.db.QueryRow(
-		`SELECT COUNT(*) FROM leases WHERE expire_at BETWEEN ? AND ? AND deleted_at IS NULL`,
+		`SELECT COUNT(*) FROM leases WHERE expire_at BETWEEN ? AND ? AND deleted_at IS NULL AND tombstoned_at IS NULL`,
 		iso(now),
 		iso(in7days),
 	).Scan(&stats.ExpiringIn7Days)
 
 	return stats, nil
 }
-
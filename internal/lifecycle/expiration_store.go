@@ -0,0 +1,89 @@
+package lifecycle
+
+import (
+	"database/sql"
+	"time"
+)
+
+// GetActiveLeaseExpirations returns (lease_id, expire_at) for every lease
+// that is still live, i.e. not tombstoned or physically deleted. The
+// expiration worker uses this once, at startup, to seed its heap; after
+// that it learns about new leases from NotifyInserted instead of
+// re-querying the whole table.
+func (s *SQLiteStore) GetActiveLeaseExpirations() ([]leaseExpiration, error) {
+	rows, err := s.db.Query(`
+SELECT lease_id, expire_at
+FROM leases
+WHERE deleted_at IS NULL AND tombstoned_at IS NULL
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var expirations []leaseExpiration
+	for rows.Next() {
+		var leaseID, expireStr string
+		if err := rows.Scan(&leaseID, &expireStr); err != nil {
+			return nil, err
+		}
+		expireAt, _ := time.Parse(time.RFC3339Nano, expireStr)
+		expirations = append(expirations, leaseExpiration{LeaseID: leaseID, ExpireAt: expireAt})
+	}
+
+	return expirations, rows.Err()
+}
+
+// GetLeaseByID fetches a single lease by its own id, for the expiration
+// worker which only ever knows a LeaseID (from the heap), not an object id
+// or bucket/key.
+func (s *SQLiteStore) GetLeaseByID(leaseID string) (*ObjectLease, error) {
+	row := s.db.QueryRow(`
+SELECT lease_id, object_id, bucket, object_key, wallet, created_at, expire_at, deleted_at, tombstoned_at, dataset_id, piece_cid
+FROM leases
+WHERE lease_id = ?
+`, leaseID)
+
+	return scanLease(row)
+}
+
+// MarkDeleted records that the lease's piece has been physically removed
+// from storage. It runs in its own transaction so the deleted_at write and
+// the row lookup that feeds the usage journal stay consistent.
+func (s *SQLiteStore) MarkDeleted(leaseID string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var wallet, datasetID string
+	var size int64
+	err = tx.QueryRow(`
+SELECT l.wallet, l.dataset_id, o.size_bytes
+FROM leases l
+JOIN objects o ON o.object_id = l.object_id
+WHERE l.lease_id = ? AND l.deleted_at IS NULL
+`, leaseID).Scan(&wallet, &datasetID, &size)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(
+		`UPDATE leases SET deleted_at = ? WHERE lease_id = ?`,
+		iso(time.Now()),
+		leaseID,
+	); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.writeUsageJournal(wallet, datasetID, usageEventDeleted, size)
+	return nil
+}
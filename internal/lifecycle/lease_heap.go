@@ -0,0 +1,32 @@
+package lifecycle
+
+import "time"
+
+// leaseExpiration is the unit the expiration worker's min-heap orders on.
+// Attempts tracks physical-delete retries so scheduleRetry can back off.
+type leaseExpiration struct {
+	LeaseID  string
+	ExpireAt time.Time
+	Attempts int
+}
+
+// leaseHeap is a container/heap.Interface ordering leaseExpiration by
+// ExpireAt, earliest first, so the worker always knows what's due next in
+// O(log n) instead of re-scanning every lease.
+type leaseHeap []leaseExpiration
+
+func (h leaseHeap) Len() int           { return len(h) }
+func (h leaseHeap) Less(i, j int) bool { return h[i].ExpireAt.Before(h[j].ExpireAt) }
+func (h leaseHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *leaseHeap) Push(x any) {
+	*h = append(*h, x.(leaseExpiration))
+}
+
+func (h *leaseHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
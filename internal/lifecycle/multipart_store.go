@@ -0,0 +1,93 @@
+package lifecycle
+
+import "time"
+
+// MultipartUpload is an in-progress S3-style multipart upload.
+type MultipartUpload struct {
+	UploadID  string
+	Bucket    string
+	Key       string
+	Wallet    string
+	DataSetID string
+	CreatedAt time.Time
+}
+
+// MultipartPart is one uploaded part of a MultipartUpload, already
+// persisted to storage as its own piece. CompleteMultipartUpload
+// reassembles parts in PartNumber order.
+type MultipartPart struct {
+	UploadID   string
+	PartNumber int
+	PieceCID   string
+	Size       int64
+	ETag       string
+}
+
+func (s *SQLiteStore) InsertMultipartUpload(u MultipartUpload) {
+	_, _ = s.db.Exec(
+		`INSERT INTO multipart_uploads(upload_id, bucket, object_key, wallet, dataset_id, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		u.UploadID, u.Bucket, u.Key, u.Wallet, u.DataSetID, iso(u.CreatedAt),
+	)
+}
+
+func (s *SQLiteStore) GetMultipartUpload(uploadID string) (*MultipartUpload, error) {
+	var u MultipartUpload
+	var createdStr string
+	err := s.db.QueryRow(`
+SELECT upload_id, bucket, object_key, wallet, dataset_id, created_at
+FROM multipart_uploads
+WHERE upload_id = ? AND completed_at IS NULL AND aborted_at IS NULL
+`, uploadID).Scan(&u.UploadID, &u.Bucket, &u.Key, &u.Wallet, &u.DataSetID, &createdStr)
+	if err != nil {
+		return nil, err
+	}
+	u.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdStr)
+	return &u, nil
+}
+
+// UpsertMultipartPart records (or overwrites, for a re-uploaded part number)
+// the piece backing a single part of an in-progress upload.
+func (s *SQLiteStore) UpsertMultipartPart(p MultipartPart) {
+	_, _ = s.db.Exec(`
+INSERT INTO multipart_parts(upload_id, part_number, piece_cid, size_bytes, etag, created_at)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT(upload_id, part_number) DO UPDATE SET
+	piece_cid = excluded.piece_cid,
+	size_bytes = excluded.size_bytes,
+	etag = excluded.etag,
+	created_at = excluded.created_at
+`, p.UploadID, p.PartNumber, p.PieceCID, p.Size, p.ETag, iso(time.Now()))
+}
+
+// ListMultipartParts returns the parts of an upload ordered by part number,
+// the order CompleteMultipartUpload must reassemble them in.
+func (s *SQLiteStore) ListMultipartParts(uploadID string) ([]MultipartPart, error) {
+	rows, err := s.db.Query(`
+SELECT upload_id, part_number, piece_cid, size_bytes, etag
+FROM multipart_parts
+WHERE upload_id = ?
+ORDER BY part_number ASC
+`, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parts []MultipartPart
+	for rows.Next() {
+		var p MultipartPart
+		if err := rows.Scan(&p.UploadID, &p.PartNumber, &p.PieceCID, &p.Size, &p.ETag); err != nil {
+			return nil, err
+		}
+		parts = append(parts, p)
+	}
+	return parts, rows.Err()
+}
+
+func (s *SQLiteStore) CompleteMultipartUpload(uploadID string) {
+	_, _ = s.db.Exec(`UPDATE multipart_uploads SET completed_at = ? WHERE upload_id = ?`, iso(time.Now()), uploadID)
+}
+
+func (s *SQLiteStore) AbortMultipartUpload(uploadID string) {
+	_, _ = s.db.Exec(`UPDATE multipart_uploads SET aborted_at = ? WHERE upload_id = ?`, iso(time.Now()), uploadID)
+}
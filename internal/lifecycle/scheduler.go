@@ -1,25 +1,205 @@
 package lifecycle
 
 import (
-	"fmt"
+	"container/heap"
+	"context"
+	"log"
 	"time"
+
+	"github.com/lvbu1984/szem-core/internal/storage"
 )
 
-func StartExpirationScheduler(store *SQLiteStore) {
-	go func() {
-		for {
-			time.Sleep(5 * time.Second)
+const (
+	// expirationBatchSize bounds how many due leases are processed per
+	// timer fire, so one pathological pile-up of expirations can't starve
+	// the notify/trigger channels.
+	expirationBatchSize = 100
+
+	expirationMinSleep   = time.Second
+	expirationIdleSleep  = 10 * time.Minute
+	expirationMaxBackoff = 10 * time.Minute
+)
+
+// ExpirationWorker replaces the old "SELECT ... WHERE expire_at < now every
+// 5 seconds" poller with a min-heap of upcoming expirations kept in memory.
+// It sleeps exactly until the earliest expiration instead of re-scanning
+// the whole leases table on a fixed tick.
+type ExpirationWorker struct {
+	store   MetaStore
+	adapter storage.Adapter
+
+	heap    leaseHeap
+	notify  chan leaseExpiration
+	trigger chan string
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func NewExpirationWorker(store MetaStore, adapter storage.Adapter) *ExpirationWorker {
+	return &ExpirationWorker{
+		store:   store,
+		adapter: adapter,
+		notify:  make(chan leaseExpiration, 256),
+		trigger: make(chan string, 16),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start seeds the heap from the MetaStore and launches the worker goroutine.
+func (w *ExpirationWorker) Start(ctx context.Context) error {
+	expirations, err := w.store.GetActiveLeaseExpirations()
+	if err != nil {
+		return err
+	}
+
+	heap.Init(&w.heap)
+	for _, exp := range expirations {
+		heap.Push(&w.heap, exp)
+	}
+
+	go w.run(ctx)
+	return nil
+}
+
+// NotifyInserted tells the worker about a newly created lease so it learns
+// about it without waiting for a reseed. Callers invoke this right after
+// the lease has been committed (MetaStore.CommitUpload).
+func (w *ExpirationWorker) NotifyInserted(l ObjectLease) {
+	select {
+	case w.notify <- leaseExpiration{LeaseID: l.LeaseID, ExpireAt: l.ExpireAt}:
+	case <-w.stop:
+	}
+}
+
+// Trigger forces immediate processing of a single lease, bypassing its
+// scheduled expiration. Intended for tests and for an explicit "delete now"
+// API path, so neither has to wait out a real timer.
+func (w *ExpirationWorker) Trigger(leaseID string) {
+	select {
+	case w.trigger <- leaseID:
+	case <-w.stop:
+	}
+}
+
+// Shutdown stops the worker and waits for its goroutine to exit, or for ctx
+// to be canceled first.
+func (w *ExpirationWorker) Shutdown(ctx context.Context) error {
+	close(w.stop)
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *ExpirationWorker) run(ctx context.Context) {
+	defer close(w.done)
+
+	timer := time.NewTimer(w.nextSleep())
+	defer timer.Stop()
 
-			leases, err := store.GetExpiredLeases()
-			if err != nil {
-				continue
-			}
+	for {
+		select {
+		case <-w.stop:
+			return
 
-			for _, lease := range leases {
-				fmt.Println(">>> Auto deleting expired lease:", lease.LeaseID)
-				store.MarkDeleted(lease.LeaseID)
-			}
+		case <-ctx.Done():
+			return
+
+		case exp := <-w.notify:
+			heap.Push(&w.heap, exp)
+			resetTimer(timer, w.nextSleep())
+
+		case leaseID := <-w.trigger:
+			w.deleteLease(ctx, leaseID, 0)
+			resetTimer(timer, w.nextSleep())
+
+		case <-timer.C:
+			w.processDue(ctx)
+			resetTimer(timer, w.nextSleep())
+		}
+	}
+}
+
+// processDue pops everything at or past its expiration, up to
+// expirationBatchSize per fire, and hands each to deleteLease.
+func (w *ExpirationWorker) processDue(ctx context.Context) {
+	now := time.Now()
+
+	for count := 0; w.heap.Len() > 0 && count < expirationBatchSize; count++ {
+		if w.heap[0].ExpireAt.After(now) {
+			return
 		}
-	}()
+		item := heap.Pop(&w.heap).(leaseExpiration)
+		w.deleteLease(ctx, item.LeaseID, item.Attempts)
+	}
 }
 
+// deleteLease runs the tombstone-then-physical-delete sequence for one
+// lease: tombstoning happens first (and is idempotent) so GET/LIST return
+// 404 immediately, then the adapter delete, then MarkDeleted. A failed
+// adapter delete is retried with exponential backoff rather than treated
+// as done, so the object never silently stays undeleted without anyone
+// noticing.
+func (w *ExpirationWorker) deleteLease(ctx context.Context, leaseID string, attempt int) {
+	lease, err := w.store.GetLeaseByID(leaseID)
+	if err != nil {
+		return // lease no longer exists; nothing to do
+	}
+	if lease.DeletedAt != nil {
+		return // already finalized, e.g. by a Trigger race with processDue
+	}
+
+	if lease.TombstonedAt == nil {
+		if err := w.store.TombstoneLease(leaseID); err != nil {
+			log.Printf("expiration: failed to tombstone lease %s: %v", leaseID, err)
+		}
+	}
+
+	if err := w.adapter.Delete(ctx, storage.PieceCID(lease.StorageRef.PieceCID)); err != nil {
+		log.Printf("expiration: adapter delete failed for lease %s (attempt %d): %v", leaseID, attempt, err)
+		w.scheduleRetry(leaseID, attempt)
+		return
+	}
+
+	if err := w.store.MarkDeleted(leaseID); err != nil {
+		log.Printf("expiration: failed to mark lease %s deleted: %v", leaseID, err)
+	}
+}
+
+func (w *ExpirationWorker) scheduleRetry(leaseID string, attempt int) {
+	backoff := time.Duration(1<<uint(attempt)) * time.Second
+	if backoff > expirationMaxBackoff {
+		backoff = expirationMaxBackoff
+	}
+	heap.Push(&w.heap, leaseExpiration{
+		LeaseID:  leaseID,
+		ExpireAt: time.Now().Add(backoff),
+		Attempts: attempt + 1,
+	})
+}
+
+// nextSleep is how long until the earliest heap entry is due, floored so a
+// burst of already-due leases can't spin the timer at zero and ceilinged so
+// an empty heap still wakes up occasionally.
+func (w *ExpirationWorker) nextSleep() time.Duration {
+	if w.heap.Len() == 0 {
+		return expirationIdleSleep
+	}
+	if d := time.Until(w.heap[0].ExpireAt); d > expirationMinSleep {
+		return d
+	}
+	return expirationMinSleep
+}
+
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
@@ -71,6 +71,113 @@ CREATE TABLE IF NOT EXISTS leases (
 	dataset_id TEXT,
 	piece_cid TEXT
 );
+`)
+	if err != nil {
+		return err
+	}
+
+	// Columns added after the original schema. SQLite has no
+	// "ADD COLUMN IF NOT EXISTS", so we just eat the "duplicate column"
+	// error on every subsequent open.
+	for _, stmt := range []string{
+		`ALTER TABLE users ADD COLUMN secret_key TEXT`,
+		`ALTER TABLE leases ADD COLUMN tombstoned_at TEXT`,
+	} {
+		_, _ = s.db.Exec(stmt)
+	}
+
+	_, err = s.db.Exec(`
+CREATE TABLE IF NOT EXISTS multipart_uploads (
+	upload_id TEXT PRIMARY KEY,
+	bucket TEXT NOT NULL,
+	object_key TEXT NOT NULL,
+	wallet TEXT NOT NULL,
+	dataset_id TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	completed_at TEXT,
+	aborted_at TEXT
+);
+
+CREATE TABLE IF NOT EXISTS multipart_parts (
+	upload_id TEXT NOT NULL,
+	part_number INTEGER NOT NULL,
+	piece_cid TEXT NOT NULL,
+	size_bytes INTEGER NOT NULL,
+	etag TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	PRIMARY KEY (upload_id, part_number)
+);
+`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+CREATE TABLE IF NOT EXISTS usage_journal (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	wallet TEXT NOT NULL,
+	dataset_id TEXT NOT NULL,
+	event_type TEXT NOT NULL,
+	size_bytes INTEGER NOT NULL,
+	day TEXT NOT NULL,
+	created_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS data_usage_cache (
+	wallet TEXT NOT NULL,
+	dataset_id TEXT NOT NULL,
+	active_bytes INTEGER NOT NULL DEFAULT 0,
+	expired_bytes INTEGER NOT NULL DEFAULT 0,
+	deleted_bytes INTEGER NOT NULL DEFAULT 0,
+	active_count INTEGER NOT NULL DEFAULT 0,
+	expired_count INTEGER NOT NULL DEFAULT 0,
+	deleted_count INTEGER NOT NULL DEFAULT 0,
+	updated_at TEXT NOT NULL,
+	PRIMARY KEY (wallet, dataset_id)
+);
+
+CREATE TABLE IF NOT EXISTS data_usage_daily (
+	wallet TEXT NOT NULL,
+	dataset_id TEXT NOT NULL,
+	day TEXT NOT NULL,
+	new_bytes INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (wallet, dataset_id, day)
+);
+
+CREATE TABLE IF NOT EXISTS usage_crawler_state (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	last_journal_id INTEGER NOT NULL DEFAULT 0
+);
+`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+CREATE TABLE IF NOT EXISTS pending_uploads (
+	id TEXT PRIMARY KEY,
+	wallet TEXT NOT NULL,
+	dataset_id TEXT NOT NULL,
+	object_id TEXT NOT NULL,
+	lease_id TEXT NOT NULL,
+	bucket TEXT,
+	object_key TEXT,
+	piece_cid TEXT,
+	size_bytes INTEGER NOT NULL,
+	created_at TEXT NOT NULL,
+	expire_at TEXT NOT NULL
+);
+`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+CREATE TABLE IF NOT EXISTS buckets (
+	bucket TEXT PRIMARY KEY,
+	wallet TEXT NOT NULL,
+	created_at TEXT NOT NULL
+);
 `)
 	return err
 }
@@ -112,6 +219,10 @@ func (s *SQLiteStore) InsertObject(objectID, wallet, datasetID string, size int6
 		size,
 		iso(time.Now()),
 	)
+
+	// The usage crawler applies this asynchronously; GetDashboardStats
+	// never has to SUM(size_bytes) over this table directly.
+	s.writeUsageJournal(wallet, datasetID, usageEventCreated, size)
 }
 
 func (s *SQLiteStore) InsertLease(l ObjectLease) {
@@ -141,48 +252,20 @@ VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 // ============================
 //
 
+// GetActiveLeaseByObjectID resolves the current lease for a legacy
+// /object/{id} route. It goes through scanLease like the bucket/key lookups
+// do, so tombstoned_at is populated here too; CalculateLeaseStatus is what
+// actually decides visibility, not this query.
 func (s *SQLiteStore) GetActiveLeaseByObjectID(objectID string) (*ObjectLease, error) {
 	row := s.db.QueryRow(`
-SELECT lease_id, object_id, wallet, created_at, expire_at, deleted_at, dataset_id, piece_cid
+SELECT lease_id, object_id, bucket, object_key, wallet, created_at, expire_at, deleted_at, tombstoned_at, dataset_id, piece_cid
 FROM leases
 WHERE object_id = ?
 ORDER BY created_at DESC
 LIMIT 1
 `, objectID)
 
-	var lease ObjectLease
-	var createdStr, expireStr string
-	var deletedStr sql.NullString
-	var datasetID, pieceCID string
-
-	err := row.Scan(
-		&lease.LeaseID,
-		&lease.ObjectID,
-		&lease.Wallet,
-		&createdStr,
-		&expireStr,
-		&deletedStr,
-		&datasetID,
-		&pieceCID,
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	lease.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdStr)
-	lease.ExpireAt, _ = time.Parse(time.RFC3339Nano, expireStr)
-
-	if deletedStr.Valid {
-		t, _ := time.Parse(time.RFC3339Nano, deletedStr.String)
-		lease.DeletedAt = &t
-	}
-
-	lease.StorageRef = StorageRef{
-		DataSetID: datasetID,
-		PieceCID:  pieceCID,
-	}
-
-	return &lease, nil
+	return scanLease(row)
 }
 
 //
@@ -203,6 +286,11 @@ type ExtendedStats struct {
 	DeletedObjects int64
 }
 
+// GetExtendedStats used to do a full table scan of leases, recomputing
+// CalculateLeaseStatus for every row on every call. The active/expired/
+// deleted breakdown now comes straight out of data_usage_cache, which the
+// usage crawler keeps current as leases are created, tombstoned and
+// physically deleted.
 func (s *SQLiteStore) GetExtendedStats() (*ExtendedStats, error) {
 	now := time.Now().UTC()
 	today := now.Truncate(24 * time.Hour)
@@ -218,63 +306,23 @@ func (s *SQLiteStore) GetExtendedStats() (*ExtendedStats, error) {
 	).Scan(&stats.NewUsersToday)
 
 	_ = s.db.QueryRow(
-		`SELECT COALESCE(SUM(size_bytes),0) FROM objects`,
+		`SELECT COALESCE(SUM(active_bytes + expired_bytes + deleted_bytes),0) FROM data_usage_cache`,
 	).Scan(&stats.TotalStorageBytes)
 
 	_ = s.db.QueryRow(
-		`SELECT COALESCE(SUM(size_bytes),0) FROM objects WHERE created_at >= ?`,
-		iso(today),
+		`SELECT COALESCE(SUM(new_bytes),0) FROM data_usage_daily WHERE day = ?`,
+		today.Format("2006-01-02"),
 	).Scan(&stats.StorageTodayBytes)
 
 	_ = s.db.QueryRow(
-		`SELECT COUNT(*) FROM leases WHERE expire_at BETWEEN ? AND ?`,
+		`SELECT COUNT(*) FROM leases WHERE expire_at BETWEEN ? AND ? AND deleted_at IS NULL AND tombstoned_at IS NULL`,
 		iso(now),
 		iso(in7days),
 	).Scan(&stats.ExpiringIn7Days)
 
-	rows, err := s.db.Query(`
-SELECT lease_id, object_id, wallet, created_at, expire_at, deleted_at
-FROM leases
-`)
-	if err != nil {
-		return stats, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var lease ObjectLease
-		var createdStr, expireStr string
-		var deletedStr sql.NullString
-
-		rows.Scan(
-			&lease.LeaseID,
-			&lease.ObjectID,
-			&lease.Wallet,
-			&createdStr,
-			&expireStr,
-			&deletedStr,
-		)
-
-		lease.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdStr)
-		lease.ExpireAt, _ = time.Parse(time.RFC3339Nano, expireStr)
-
-		if deletedStr.Valid {
-			t, _ := time.Parse(time.RFC3339Nano, deletedStr.String)
-			lease.DeletedAt = &t
-		}
-
-		status := CalculateLeaseStatus(lease)
-
-		switch status {
-		case LeaseActive:
-			stats.ActiveObjects++
-		case LeaseExpired:
-			stats.ExpiredObjects++
-		case LeaseDeleted:
-			stats.DeletedObjects++
-		}
-	}
+	_ = s.db.QueryRow(`SELECT COALESCE(SUM(active_count),0) FROM data_usage_cache`).Scan(&stats.ActiveObjects)
+	_ = s.db.QueryRow(`SELECT COALESCE(SUM(expired_count),0) FROM data_usage_cache`).Scan(&stats.ExpiredObjects)
+	_ = s.db.QueryRow(`SELECT COALESCE(SUM(deleted_count),0) FROM data_usage_cache`).Scan(&stats.DeletedObjects)
 
 	return stats, nil
 }
-
@@ -0,0 +1,54 @@
+package lifecycle
+
+// MetaStore is every metadata operation api.Server, internal/s3,
+// ExpirationWorker and UsageCrawler need. None of them should ever see a
+// concrete *SQLiteStore or *BadgerStore; they depend on this interface so
+// swapping backends is a single flag in cmd/szem, not a rewrite.
+type MetaStore interface {
+	Close() error
+
+	InsertUser(wallet string)
+	InsertDataSet(datasetID, wallet string)
+	InsertObject(objectID, wallet, datasetID string, size int64)
+	InsertLease(l ObjectLease)
+
+	GetActiveLeaseByObjectID(objectID string) (*ObjectLease, error)
+	GetActiveLeaseByBucketKey(bucket, key string) (*ObjectLease, error)
+	ListObjectsByBucket(bucket, prefix, startAfter string, maxKeys int) ([]ObjectLease, error)
+	ListObjectsByWallet(wallet string) ([]ObjectLease, error)
+
+	TombstoneLease(leaseID string) error
+	MarkDeleted(leaseID string) error
+	GetLeaseByID(leaseID string) (*ObjectLease, error)
+	GetActiveLeaseExpirations() ([]leaseExpiration, error)
+
+	GetOrCreateCredential(wallet string) (string, error)
+	GetWalletForAccessKey(accessKeyID string) (wallet, secretKey string, err error)
+
+	EnsureBucketOwnership(bucket, wallet string) error
+	BucketOwner(bucket string) (wallet string, ok bool, err error)
+
+	InsertMultipartUpload(u MultipartUpload)
+	GetMultipartUpload(uploadID string) (*MultipartUpload, error)
+	UpsertMultipartPart(p MultipartPart)
+	ListMultipartParts(uploadID string) ([]MultipartPart, error)
+	CompleteMultipartUpload(uploadID string)
+	AbortMultipartUpload(uploadID string)
+
+	GetDashboardStats() (*DashboardStats, error)
+	GetExtendedStats() (*ExtendedStats, error)
+	GetUsageBreakdown(wallet string) (*UsageBreakdown, error)
+
+	GetUsageCursor() (int64, error)
+	SetUsageCursor(cursor int64) error
+	FetchUsageJournalBatch(afterID int64, limit int) ([]usageJournalEntry, error)
+	ApplyUsageDelta(entry usageJournalEntry) error
+
+	RecordUploadIntent(p PendingUpload) error
+	SetUploadIntentPieceCID(id, pieceCID string) error
+	CommitUpload(p PendingUpload) error
+	DropUploadIntent(id string) error
+	ListPendingUploads() ([]PendingUpload, error)
+}
+
+var _ MetaStore = (*SQLiteStore)(nil)
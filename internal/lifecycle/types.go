@@ -17,7 +17,10 @@ const (
 func CalculateLeaseStatus(l ObjectLease) LeaseStatus {
 	now := time.Now().UTC()
 
-	if l.DeletedAt != nil {
+	// Tombstoning is the "must be invisible to GET/LIST right now" signal
+	// and runs ahead of the physical delete, so it's treated the same as
+	// DeletedAt here rather than only at the S3 route.
+	if l.DeletedAt != nil || l.TombstonedAt != nil {
 		return LeaseDeleted
 	}
 
@@ -54,10 +57,15 @@ type ObjectLease struct {
 	// StorageRef binds this lease/object to FWSS piece identity.
 	// Keep explicit so the system is not a black box.
 	StorageRef StorageRef
+
+	// Size is the object's payload size in bytes. It is not a lease column
+	// in SQLiteStore; callers that need it (e.g. S3 ListObjectsV2) join it
+	// in from objects. BadgerStore has no join, so it stores Size on the
+	// lease record itself.
+	Size int64
 }
 
 type StorageRef struct {
 	DataSetID string
 	PieceCID  string
 }
-
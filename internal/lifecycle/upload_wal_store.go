@@ -0,0 +1,110 @@
+package lifecycle
+
+import (
+	"database/sql"
+	"time"
+)
+
+// RecordUploadIntent writes the journal entry before adapter.Upload runs.
+// PieceCID is empty at this point; SetUploadIntentPieceCID fills it in once
+// the upload succeeds.
+func (s *SQLiteStore) RecordUploadIntent(p PendingUpload) error {
+	_, err := s.db.Exec(`
+INSERT INTO pending_uploads(id, wallet, dataset_id, object_id, lease_id, bucket, object_key, piece_cid, size_bytes, created_at, expire_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`,
+		p.ID, p.Wallet, p.DataSetID, p.ObjectID, p.LeaseID, p.Bucket, p.Key, "", p.Size, iso(p.CreatedAt), iso(p.ExpireAt),
+	)
+	return err
+}
+
+func (s *SQLiteStore) SetUploadIntentPieceCID(id, pieceCID string) error {
+	_, err := s.db.Exec(`UPDATE pending_uploads SET piece_cid = ? WHERE id = ?`, pieceCID, id)
+	return err
+}
+
+// CommitUpload is the single transaction handleUpload and s3.storeObject
+// used to run as four unguarded statements. All four metadata rows land
+// together, and the journal entry is removed in the same transaction, so a
+// crash either lands before this call (caught by PieceCID replay) or after
+// it (nothing left to replay).
+func (s *SQLiteStore) CommitUpload(p PendingUpload) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := iso(time.Now())
+
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO users(wallet, created_at) VALUES (?, ?)`, p.Wallet, now); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO datasets(dataset_id, wallet, created_at) VALUES (?, ?, ?)`, p.DataSetID, p.Wallet, now); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO objects(object_id, wallet, dataset_id, size_bytes, created_at) VALUES (?, ?, ?, ?, ?)`,
+		p.ObjectID, p.Wallet, p.DataSetID, p.Size, now,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+INSERT INTO leases(lease_id, object_id, bucket, object_key, wallet, created_at, expire_at, deleted_at, dataset_id, piece_cid)
+VALUES (?, ?, ?, ?, ?, ?, ?, NULL, ?, ?)
+`, p.LeaseID, p.ObjectID, p.Bucket, p.Key, p.Wallet, iso(p.CreatedAt), iso(p.ExpireAt), p.DataSetID, p.PieceCID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM pending_uploads WHERE id = ?`, p.ID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.writeUsageJournal(p.Wallet, p.DataSetID, usageEventCreated, p.Size)
+	return nil
+}
+
+func (s *SQLiteStore) DropUploadIntent(id string) error {
+	_, err := s.db.Exec(`DELETE FROM pending_uploads WHERE id = ?`, id)
+	return err
+}
+
+// ListPendingUploads returns every journal entry left over from a previous
+// run, for startup replay.
+func (s *SQLiteStore) ListPendingUploads() ([]PendingUpload, error) {
+	rows, err := s.db.Query(`
+SELECT id, wallet, dataset_id, object_id, lease_id, bucket, object_key, piece_cid, size_bytes, created_at, expire_at
+FROM pending_uploads
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []PendingUpload
+	for rows.Next() {
+		var p PendingUpload
+		var bucket, key, pieceCID sql.NullString
+		var createdStr, expireStr string
+
+		if err := rows.Scan(
+			&p.ID, &p.Wallet, &p.DataSetID, &p.ObjectID, &p.LeaseID,
+			&bucket, &key, &pieceCID, &p.Size, &createdStr, &expireStr,
+		); err != nil {
+			return nil, err
+		}
+
+		p.Bucket = bucket.String
+		p.Key = key.String
+		p.PieceCID = pieceCID.String
+		p.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdStr)
+		p.ExpireAt, _ = time.Parse(time.RFC3339Nano, expireStr)
+
+		pending = append(pending, p)
+	}
+
+	return pending, rows.Err()
+}
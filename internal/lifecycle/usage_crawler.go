@@ -0,0 +1,88 @@
+package lifecycle
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	// usageCrawlerBatchSize bounds rows applied per tick, the "bounded
+	// chunks" the crawler walks the journal in.
+	usageCrawlerBatchSize = 1000
+	usageCrawlerTick      = 2 * time.Second
+)
+
+// UsageCrawler keeps data_usage_cache (and its 30-day daily histogram) in
+// sync with usage_journal so GetDashboardStats/GetExtendedStats never have
+// to SUM() leases/objects directly. It only ever walks forward from a
+// persisted cursor, so a crash mid-tick just resumes rather than redoing
+// work or double-applying a delta.
+type UsageCrawler struct {
+	store MetaStore
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+func NewUsageCrawler(store MetaStore) *UsageCrawler {
+	return &UsageCrawler{
+		store: store,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+func (c *UsageCrawler) Start(ctx context.Context) {
+	go c.run(ctx)
+}
+
+func (c *UsageCrawler) Shutdown(ctx context.Context) error {
+	close(c.stop)
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *UsageCrawler) run(ctx context.Context) {
+	defer close(c.done)
+
+	ticker := time.NewTicker(usageCrawlerTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.tick(); err != nil {
+				log.Printf("usage crawler: %v", err)
+			}
+		}
+	}
+}
+
+func (c *UsageCrawler) tick() error {
+	cursor, err := c.store.GetUsageCursor()
+	if err != nil {
+		return err
+	}
+
+	entries, err := c.store.FetchUsageJournalBatch(cursor, usageCrawlerBatchSize)
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := c.store.ApplyUsageDelta(entry); err != nil {
+			return err
+		}
+		cursor = entry.ID
+	}
+
+	return c.store.SetUsageCursor(cursor)
+}
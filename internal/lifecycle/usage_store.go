@@ -0,0 +1,220 @@
+package lifecycle
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Usage journal event types. Each one corresponds to exactly one state
+// transition a lease/object goes through; the usage crawler folds them
+// into data_usage_cache in order.
+const (
+	usageEventCreated = "created"
+	usageEventExpired = "expired"
+	usageEventDeleted = "deleted"
+)
+
+type usageJournalEntry struct {
+	ID        int64
+	Wallet    string
+	DataSetID string
+	EventType string
+	SizeBytes int64
+	Day       string
+}
+
+// DatasetUsage is one (wallet, dataset) row of the usage cache.
+type DatasetUsage struct {
+	DataSetID    string
+	ActiveBytes  int64
+	ExpiredBytes int64
+	DeletedBytes int64
+	ActiveCount  int64
+	ExpiredCount int64
+	DeletedCount int64
+}
+
+// DailyUsage is one day's worth of newly-ingested bytes, for the
+// dashboard's 30-day histogram.
+type DailyUsage struct {
+	Day      string
+	NewBytes int64
+}
+
+// UsageBreakdown is the payload for GET /dashboard/usage?wallet=...
+type UsageBreakdown struct {
+	Wallet   string
+	Datasets []DatasetUsage
+	Daily    []DailyUsage
+}
+
+// writeUsageJournal appends an intent record for the usage crawler to pick
+// up; it never updates data_usage_cache directly, so every cache write
+// goes through ApplyUsageDelta and is trivially idempotent/resumable.
+func (s *SQLiteStore) writeUsageJournal(wallet, datasetID, eventType string, size int64) {
+	now := time.Now().UTC()
+	_, _ = s.db.Exec(`
+INSERT INTO usage_journal(wallet, dataset_id, event_type, size_bytes, day, created_at)
+VALUES (?, ?, ?, ?, ?, ?)
+`, wallet, datasetID, eventType, size, now.Format("2006-01-02"), iso(now))
+}
+
+// GetUsageCursor returns the last usage_journal id the crawler has applied,
+// initializing the single-row cursor table on first use.
+func (s *SQLiteStore) GetUsageCursor() (int64, error) {
+	var cursor int64
+	err := s.db.QueryRow(`SELECT last_journal_id FROM usage_crawler_state WHERE id = 1`).Scan(&cursor)
+	if err == sql.ErrNoRows {
+		if _, err := s.db.Exec(`INSERT OR IGNORE INTO usage_crawler_state(id, last_journal_id) VALUES (1, 0)`); err != nil {
+			return 0, err
+		}
+		return 0, nil
+	}
+	return cursor, err
+}
+
+func (s *SQLiteStore) SetUsageCursor(cursor int64) error {
+	_, err := s.db.Exec(`UPDATE usage_crawler_state SET last_journal_id = ? WHERE id = 1`, cursor)
+	return err
+}
+
+// FetchUsageJournalBatch returns up to limit journal rows after afterID, in
+// order, the bounded chunk the crawler walks per tick.
+func (s *SQLiteStore) FetchUsageJournalBatch(afterID int64, limit int) ([]usageJournalEntry, error) {
+	rows, err := s.db.Query(`
+SELECT id, wallet, dataset_id, event_type, size_bytes, day
+FROM usage_journal
+WHERE id > ?
+ORDER BY id ASC
+LIMIT ?
+`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []usageJournalEntry
+	for rows.Next() {
+		var e usageJournalEntry
+		if err := rows.Scan(&e.ID, &e.Wallet, &e.DataSetID, &e.EventType, &e.SizeBytes, &e.Day); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// ApplyUsageDelta folds one journal entry into data_usage_cache (and, for
+// creates, into the data_usage_daily histogram), keyed by (wallet,
+// dataset_id). It's the only writer of the cache table.
+func (s *SQLiteStore) ApplyUsageDelta(entry usageJournalEntry) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := iso(time.Now())
+
+	if _, err := tx.Exec(`
+INSERT INTO data_usage_cache(wallet, dataset_id, active_bytes, expired_bytes, deleted_bytes, active_count, expired_count, deleted_count, updated_at)
+VALUES (?, ?, 0, 0, 0, 0, 0, 0, ?)
+ON CONFLICT(wallet, dataset_id) DO NOTHING
+`, entry.Wallet, entry.DataSetID, now); err != nil {
+		return err
+	}
+
+	switch entry.EventType {
+	case usageEventCreated:
+		if _, err := tx.Exec(`
+UPDATE data_usage_cache
+SET active_bytes = active_bytes + ?, active_count = active_count + 1, updated_at = ?
+WHERE wallet = ? AND dataset_id = ?
+`, entry.SizeBytes, now, entry.Wallet, entry.DataSetID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+INSERT INTO data_usage_daily(wallet, dataset_id, day, new_bytes)
+VALUES (?, ?, ?, ?)
+ON CONFLICT(wallet, dataset_id, day) DO UPDATE SET new_bytes = new_bytes + excluded.new_bytes
+`, entry.Wallet, entry.DataSetID, entry.Day, entry.SizeBytes); err != nil {
+			return err
+		}
+
+	case usageEventExpired:
+		if _, err := tx.Exec(`
+UPDATE data_usage_cache
+SET active_bytes = active_bytes - ?, expired_bytes = expired_bytes + ?,
+    active_count = active_count - 1, expired_count = expired_count + 1,
+    updated_at = ?
+WHERE wallet = ? AND dataset_id = ?
+`, entry.SizeBytes, entry.SizeBytes, now, entry.Wallet, entry.DataSetID); err != nil {
+			return err
+		}
+
+	case usageEventDeleted:
+		if _, err := tx.Exec(`
+UPDATE data_usage_cache
+SET expired_bytes = expired_bytes - ?, deleted_bytes = deleted_bytes + ?,
+    expired_count = expired_count - 1, deleted_count = deleted_count + 1,
+    updated_at = ?
+WHERE wallet = ? AND dataset_id = ?
+`, entry.SizeBytes, entry.SizeBytes, now, entry.Wallet, entry.DataSetID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetUsageBreakdown returns the per-dataset cache rows for a wallet plus
+// its last-30-days new-bytes histogram, the payload behind
+// GET /dashboard/usage?wallet=...
+func (s *SQLiteStore) GetUsageBreakdown(wallet string) (*UsageBreakdown, error) {
+	breakdown := &UsageBreakdown{Wallet: wallet}
+
+	rows, err := s.db.Query(`
+SELECT dataset_id, active_bytes, expired_bytes, deleted_bytes, active_count, expired_count, deleted_count
+FROM data_usage_cache
+WHERE wallet = ?
+ORDER BY dataset_id
+`, wallet)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d DatasetUsage
+		if err := rows.Scan(&d.DataSetID, &d.ActiveBytes, &d.ExpiredBytes, &d.DeletedBytes, &d.ActiveCount, &d.ExpiredCount, &d.DeletedCount); err != nil {
+			return nil, err
+		}
+		breakdown.Datasets = append(breakdown.Datasets, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	since := time.Now().UTC().AddDate(0, 0, -30).Format("2006-01-02")
+	dailyRows, err := s.db.Query(`
+SELECT day, SUM(new_bytes)
+FROM data_usage_daily
+WHERE wallet = ? AND day >= ?
+GROUP BY day
+ORDER BY day
+`, wallet, since)
+	if err != nil {
+		return nil, err
+	}
+	defer dailyRows.Close()
+
+	for dailyRows.Next() {
+		var d DailyUsage
+		if err := dailyRows.Scan(&d.Day, &d.NewBytes); err != nil {
+			return nil, err
+		}
+		breakdown.Daily = append(breakdown.Daily, d)
+	}
+
+	return breakdown, dailyRows.Err()
+}
@@ -0,0 +1,188 @@
+package s3
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/lvbu1984/szem-core/internal/lifecycle"
+	"github.com/lvbu1984/szem-core/internal/storage"
+)
+
+func partNumberFromQuery(r *http.Request) (int, error) {
+	raw := r.URL.Query().Get("partNumber")
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		return 0, errors.New("partNumber must be a positive integer")
+	}
+	return n, nil
+}
+
+func (s *Server) createMultipartUpload(w http.ResponseWriter, r *http.Request, wallet, bucket, key string) {
+	if err := s.store.EnsureBucketOwnership(bucket, wallet); errors.Is(err, lifecycle.ErrBucketOwnedByOther) {
+		writeS3Error(w, r, http.StatusForbidden, "AccessDenied", "bucket is owned by a different wallet")
+		return
+	} else if err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", "failed to check bucket ownership")
+		return
+	}
+
+	dataSetID, err := s.adapter.EnsureDataSet(r.Context(), storage.DataSetMeta{
+		Application: "Qave",
+		Version:     "1.0",
+	})
+	if err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", "failed to ensure dataset")
+		return
+	}
+
+	uploadID := uuid.New().String()
+	s.store.InsertMultipartUpload(lifecycle.MultipartUpload{
+		UploadID:  uploadID,
+		Bucket:    bucket,
+		Key:       key,
+		Wallet:    wallet,
+		DataSetID: string(dataSetID),
+	})
+
+	writeXML(w, http.StatusOK, initiateMultipartUploadResult{
+		Bucket:   bucket,
+		Key:      key,
+		UploadID: uploadID,
+	})
+}
+
+func (s *Server) uploadPart(w http.ResponseWriter, r *http.Request, wallet, bucket, key string) {
+	uploadID := r.URL.Query().Get("uploadId")
+	partNumber, err := partNumberFromQuery(r)
+	if err != nil {
+		writeS3Error(w, r, http.StatusBadRequest, "InvalidArgument", err.Error())
+		return
+	}
+
+	upload, err := s.store.GetMultipartUpload(uploadID)
+	if err != nil {
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchUpload", "upload not found")
+		return
+	}
+	if upload.Wallet != wallet {
+		writeS3Error(w, r, http.StatusForbidden, "AccessDenied", "upload belongs to a different wallet")
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeS3Error(w, r, http.StatusBadRequest, "IncompleteBody", "failed to read part body")
+		return
+	}
+	if err := verifyPayloadHash(r, data); err != nil {
+		writeS3Error(w, r, http.StatusBadRequest, "XAmzContentSHA256Mismatch", err.Error())
+		return
+	}
+
+	uploadResult, err := s.adapter.Upload(r.Context(), storage.DataSetID(upload.DataSetID), data, storage.UploadOptions{FileName: key, Wallet: wallet})
+	if err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", "part upload failed")
+		return
+	}
+
+	etag := etagFor(uploadResult.PieceCID)
+	s.store.UpsertMultipartPart(lifecycle.MultipartPart{
+		UploadID:   uploadID,
+		PartNumber: partNumber,
+		PieceCID:   string(uploadResult.PieceCID),
+		Size:       int64(uploadResult.Size),
+		ETag:       etag,
+	})
+
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusOK)
+}
+
+// validateCompletedParts checks the client's CompleteMultipartUpload
+// manifest against what was actually uploaded: same number of parts, same
+// part numbers in the same order, and a matching ETag for each. Without
+// this, completing with a stale or partial manifest silently reassembled
+// the server's current part set instead of the one the client asked for.
+func validateCompletedParts(requested []completedPartRequest, stored []lifecycle.MultipartPart) error {
+	if len(requested) != len(stored) {
+		return fmt.Errorf("manifest lists %d parts, %d were uploaded", len(requested), len(stored))
+	}
+	for i, req := range requested {
+		if req.PartNumber != stored[i].PartNumber {
+			return fmt.Errorf("manifest part %d: expected part number %d, got %d", i+1, stored[i].PartNumber, req.PartNumber)
+		}
+		if req.ETag != stored[i].ETag {
+			return fmt.Errorf("manifest part %d: ETag does not match uploaded part", req.PartNumber)
+		}
+	}
+	return nil
+}
+
+func (s *Server) completeMultipartUpload(w http.ResponseWriter, r *http.Request, wallet, bucket, key string) {
+	uploadID := r.URL.Query().Get("uploadId")
+
+	upload, err := s.store.GetMultipartUpload(uploadID)
+	if err != nil {
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchUpload", "upload not found")
+		return
+	}
+	if upload.Wallet != wallet {
+		writeS3Error(w, r, http.StatusForbidden, "AccessDenied", "upload belongs to a different wallet")
+		return
+	}
+
+	var req completeMultipartUpload
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeS3Error(w, r, http.StatusBadRequest, "MalformedXML", "could not parse CompleteMultipartUpload body")
+		return
+	}
+
+	parts, err := s.store.ListMultipartParts(uploadID)
+	if err != nil || len(parts) == 0 {
+		writeS3Error(w, r, http.StatusBadRequest, "InvalidPart", "no parts uploaded")
+		return
+	}
+
+	if err := validateCompletedParts(req.Parts, parts); err != nil {
+		writeS3Error(w, r, http.StatusBadRequest, "InvalidPart", err.Error())
+		return
+	}
+
+	// Reassemble the object by downloading each piece in order and
+	// re-uploading as a single final piece. This keeps the final object
+	// addressable by one PieceCID like any other upload, at the cost of an
+	// extra copy; a real FWSS backend would instead stitch piece references
+	// without moving bytes.
+	var assembled []byte
+	for _, part := range parts {
+		chunk, err := s.adapter.Download(r.Context(), storage.PieceCID(part.PieceCID))
+		if err != nil {
+			writeS3Error(w, r, http.StatusInternalServerError, "InternalError", "failed to reassemble parts")
+			return
+		}
+		assembled = append(assembled, chunk...)
+	}
+
+	lease, uploadResult, err := s.storeObject(r.Context(), upload.Wallet, bucket, key, assembled)
+	if err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", "failed to store completed object")
+		return
+	}
+
+	for _, part := range parts {
+		_ = s.adapter.Delete(r.Context(), storage.PieceCID(part.PieceCID))
+	}
+	s.store.CompleteMultipartUpload(uploadID)
+
+	_ = lease
+	writeXML(w, http.StatusOK, completeMultipartUploadResult{
+		Bucket: bucket,
+		Key:    key,
+		ETag:   etagFor(uploadResult.PieceCID),
+	})
+}
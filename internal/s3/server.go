@@ -0,0 +1,335 @@
+// Package s3 implements an S3-compatible HTTP surface on top of the same
+// lease/storage pipeline the legacy /upload and /object routes use. It is
+// deliberately thin: every handler here resolves to a lifecycle.MetaStore
+// call and a storage.Adapter call, nothing more, so existing S3 SDKs can
+// point at Qave without the server needing to know anything about them.
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lvbu1984/szem-core/internal/lifecycle"
+	"github.com/lvbu1984/szem-core/internal/storage"
+)
+
+const defaultLeaseTTL = 30 * 24 * time.Hour
+
+// Server handles the S3-compatible subset of requests. It is mounted as the
+// catch-all route by api.Server, behind the legacy /upload, /object/,
+// /objects, /dashboard, /dashboard/usage, /credentials and /health routes.
+type Server struct {
+	store   lifecycle.MetaStore
+	adapter storage.Adapter
+	expiry  *lifecycle.ExpirationWorker
+}
+
+func NewServer(store lifecycle.MetaStore, adapter storage.Adapter, expiry *lifecycle.ExpirationWorker) *Server {
+	return &Server{store: store, adapter: adapter, expiry: expiry}
+}
+
+// Handler authenticates the request with SigV4 and dispatches it to the
+// matching S3 operation. It is registered by api.Server as the fallback
+// handler for any path not already claimed by a legacy route.
+func (s *Server) Handler(w http.ResponseWriter, r *http.Request) {
+	wallet, err := verifySigV4(r, func(accessKeyID string) (string, error) {
+		_, secret, err := s.store.GetWalletForAccessKey(accessKeyID)
+		return secret, err
+	})
+	if err != nil {
+		writeS3Error(w, r, http.StatusForbidden, "SignatureDoesNotMatch", err.Error())
+		return
+	}
+
+	bucket, key := splitBucketKey(r.URL.Path)
+	if bucket == "" {
+		writeS3Error(w, r, http.StatusBadRequest, "InvalidBucketName", "bucket is required")
+		return
+	}
+
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		s.listObjectsV2(w, r, wallet, bucket)
+	case r.Method == http.MethodPut && r.URL.Query().Has("partNumber") && r.URL.Query().Has("uploadId"):
+		s.uploadPart(w, r, wallet, bucket, key)
+	case r.Method == http.MethodPost && r.URL.Query().Has("uploads"):
+		s.createMultipartUpload(w, r, wallet, bucket, key)
+	case r.Method == http.MethodPost && r.URL.Query().Has("uploadId"):
+		s.completeMultipartUpload(w, r, wallet, bucket, key)
+	case r.Method == http.MethodPut:
+		s.putObject(w, r, wallet, bucket, key)
+	case r.Method == http.MethodGet:
+		s.getObject(w, r, wallet, bucket, key, true)
+	case r.Method == http.MethodHead:
+		s.getObject(w, r, wallet, bucket, key, false)
+	case r.Method == http.MethodDelete:
+		s.deleteObject(w, r, wallet, bucket, key)
+	default:
+		writeS3Error(w, r, http.StatusMethodNotAllowed, "MethodNotAllowed", "unsupported method for this route")
+	}
+}
+
+// splitBucketKey turns "/mybucket/a/b/c.txt" into ("mybucket", "a/b/c.txt"),
+// matching S3 path-style addressing.
+func splitBucketKey(path string) (bucket, key string) {
+	trimmed := strings.TrimPrefix(path, "/")
+	parts := strings.SplitN(trimmed, "/", 2)
+	bucket = parts[0]
+	if len(parts) == 2 {
+		key = parts[1]
+	}
+	return bucket, key
+}
+
+func (s *Server) putObject(w http.ResponseWriter, r *http.Request, wallet, bucket, key string) {
+	if err := s.store.EnsureBucketOwnership(bucket, wallet); errors.Is(err, lifecycle.ErrBucketOwnedByOther) {
+		writeS3Error(w, r, http.StatusForbidden, "AccessDenied", "bucket is owned by a different wallet")
+		return
+	} else if err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", "failed to check bucket ownership")
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeS3Error(w, r, http.StatusBadRequest, "IncompleteBody", "failed to read request body")
+		return
+	}
+	if err := verifyPayloadHash(r, data); err != nil {
+		writeS3Error(w, r, http.StatusBadRequest, "XAmzContentSHA256Mismatch", err.Error())
+		return
+	}
+
+	lease, uploadResult, err := s.storeObject(r.Context(), wallet, bucket, key, data)
+	if err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", "upload failed")
+		return
+	}
+
+	w.Header().Set("ETag", etagFor(uploadResult.PieceCID))
+	w.Header().Set("X-Amz-Expiration", lease.ExpireAt.Format(time.RFC1123))
+	w.WriteHeader(http.StatusOK)
+}
+
+// storeObject runs the same EnsureDataSet -> Upload -> CommitUpload
+// pipeline as api.Server.handleUpload, but keyed by bucket/key instead of a
+// generated object id. It goes through the same upload write-ahead log:
+// intent is recorded before adapter.Upload, and the metadata rows only land
+// once the upload has succeeded.
+func (s *Server) storeObject(ctx context.Context, wallet, bucket, key string, data []byte) (*lifecycle.ObjectLease, *storage.UploadResult, error) {
+	dataSetID, err := s.adapter.EnsureDataSet(ctx, storage.DataSetMeta{
+		Application: "Qave",
+		Version:     "1.0",
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("ensure dataset: %w", err)
+	}
+
+	now := time.Now().UTC()
+	intent := lifecycle.PendingUpload{
+		ID:        uuid.New().String(),
+		Wallet:    wallet,
+		DataSetID: string(dataSetID),
+		ObjectID:  uuid.New().String(),
+		LeaseID:   uuid.New().String(),
+		Bucket:    bucket,
+		Key:       key,
+		Size:      int64(len(data)),
+		CreatedAt: now,
+		ExpireAt:  now.Add(defaultLeaseTTL),
+	}
+
+	if err := s.store.RecordUploadIntent(intent); err != nil {
+		return nil, nil, fmt.Errorf("record upload intent: %w", err)
+	}
+
+	uploadResult, err := s.adapter.Upload(ctx, dataSetID, data, storage.UploadOptions{FileName: key, Wallet: wallet})
+	if err != nil {
+		_ = s.store.DropUploadIntent(intent.ID)
+		return nil, nil, fmt.Errorf("upload: %w", err)
+	}
+	intent.PieceCID = string(uploadResult.PieceCID)
+
+	if err := s.store.SetUploadIntentPieceCID(intent.ID, intent.PieceCID); err != nil {
+		return nil, nil, fmt.Errorf("record piece cid: %w", err)
+	}
+	if err := s.store.CommitUpload(intent); err != nil {
+		return nil, nil, fmt.Errorf("commit upload: %w", err)
+	}
+
+	lease := lifecycle.ObjectLease{
+		LeaseID:   intent.LeaseID,
+		ObjectID:  intent.ObjectID,
+		Bucket:    bucket,
+		Key:       key,
+		Wallet:    wallet,
+		CreatedAt: now,
+		ExpireAt:  intent.ExpireAt,
+		StorageRef: lifecycle.StorageRef{
+			DataSetID: string(dataSetID),
+			PieceCID:  intent.PieceCID,
+		},
+	}
+	s.expiry.NotifyInserted(lease)
+
+	return &lease, uploadResult, nil
+}
+
+func (s *Server) getObject(w http.ResponseWriter, r *http.Request, wallet, bucket, key string, withBody bool) {
+	owner, ok, err := s.store.BucketOwner(bucket)
+	if err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", "failed to check bucket ownership")
+		return
+	}
+	if ok && owner != wallet {
+		writeS3Error(w, r, http.StatusForbidden, "AccessDenied", "bucket is owned by a different wallet")
+		return
+	}
+
+	lease, err := s.resolveVisibleLease(bucket, key)
+	if err != nil {
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchKey", "object not found")
+		return
+	}
+
+	w.Header().Set("ETag", etagFor(storage.PieceCID(lease.StorageRef.PieceCID)))
+	w.Header().Set("Last-Modified", lease.CreatedAt.Format(time.RFC1123))
+
+	if !withBody {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	data, err := s.adapter.Download(r.Context(), storage.PieceCID(lease.StorageRef.PieceCID))
+	if err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", "download failed")
+		return
+	}
+
+	w.Write(data)
+}
+
+func (s *Server) deleteObject(w http.ResponseWriter, r *http.Request, wallet, bucket, key string) {
+	owner, ok, err := s.store.BucketOwner(bucket)
+	if err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", "failed to check bucket ownership")
+		return
+	}
+	if ok && owner != wallet {
+		writeS3Error(w, r, http.StatusForbidden, "AccessDenied", "bucket is owned by a different wallet")
+		return
+	}
+
+	lease, err := s.resolveVisibleLease(bucket, key)
+	if err != nil {
+		// S3's DeleteObject is idempotent: deleting something already gone
+		// is still a 204, not a 404.
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if err := s.store.TombstoneLease(lease.LeaseID); err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", "delete failed")
+		return
+	}
+
+	w.Header().Set("X-Amz-Delete-Marker", "true")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resolveVisibleLease returns the lease for bucket/key only if it is
+// neither tombstoned, deleted, nor expired. CalculateLeaseStatus is the
+// single source of truth for all three; the legacy /object/{id} route
+// checks the same function.
+func (s *Server) resolveVisibleLease(bucket, key string) (*lifecycle.ObjectLease, error) {
+	lease, err := s.store.GetActiveLeaseByBucketKey(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	if lifecycle.CalculateLeaseStatus(*lease) != lifecycle.LeaseActive {
+		return nil, fmt.Errorf("not active")
+	}
+	return lease, nil
+}
+
+func (s *Server) listObjectsV2(w http.ResponseWriter, r *http.Request, wallet, bucket string) {
+	owner, ok, err := s.store.BucketOwner(bucket)
+	if err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", "failed to check bucket ownership")
+		return
+	}
+	if ok && owner != wallet {
+		writeS3Error(w, r, http.StatusForbidden, "AccessDenied", "bucket is owned by a different wallet")
+		return
+	}
+
+	q := r.URL.Query()
+	prefix := q.Get("prefix")
+	startAfter := q.Get("start-after")
+
+	maxKeys := 1000
+	if raw := q.Get("max-keys"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			maxKeys = n
+		}
+	}
+
+	leases, err := s.store.ListObjectsByBucket(bucket, prefix, startAfter, maxKeys+1)
+	if err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", "list failed")
+		return
+	}
+
+	truncated := len(leases) > maxKeys
+	if truncated {
+		leases = leases[:maxKeys]
+	}
+
+	result := listBucketResult{
+		Name:        bucket,
+		Prefix:      prefix,
+		StartAfter:  startAfter,
+		KeyCount:    len(leases),
+		MaxKeys:     maxKeys,
+		IsTruncated: truncated,
+	}
+	for _, lease := range leases {
+		result.Contents = append(result.Contents, objectSummary{
+			Key:          lease.Key,
+			LastModified: lease.CreatedAt.UTC().Format(time.RFC3339),
+			ETag:         etagFor(storage.PieceCID(lease.StorageRef.PieceCID)),
+			Size:         lease.Size,
+			StorageClass: "STANDARD",
+		})
+	}
+
+	writeXML(w, http.StatusOK, result)
+}
+
+func etagFor(pieceCID storage.PieceCID) string {
+	return `"` + string(pieceCID) + `"`
+}
+
+func writeXML(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	w.Write([]byte(xml.Header))
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+func writeS3Error(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	writeXML(w, status, errorResponse{
+		Code:      code,
+		Message:   message,
+		Resource:  r.URL.Path,
+		RequestID: w.Header().Get("X-Request-Id"),
+	})
+}
@@ -7,6 +7,10 @@ type PieceCID string
 
 type UploadOptions struct {
 	FileName string
+
+	// Wallet, when known, is recorded in FileAdapter's piece header for
+	// auditability. Adapters that don't need it (MockAdapter) ignore it.
+	Wallet string
 }
 
 type UploadResult struct {
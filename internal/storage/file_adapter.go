@@ -0,0 +1,263 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	_ "modernc.org/sqlite"
+)
+
+// FileAdapter persists every uploaded blob as a self-describing "piece
+// file" on the local filesystem: a fixed magic, a storage-format version,
+// a serialized header, then the payload. The header is the source of truth
+// for piece metadata, so the SQLite index can be fully rebuilt from the
+// on-disk piece directory alone (RebuildIndex); the index only exists so
+// expiry scans and lookups don't have to walk the directory tree every time.
+type FileAdapter struct {
+	baseDir string
+	index   *sql.DB
+}
+
+// NewFileAdapter opens (creating if needed) a piece directory rooted at
+// baseDir, along with its piece_expirations index.
+func NewFileAdapter(baseDir string) (*FileAdapter, error) {
+	if err := os.MkdirAll(baseDir, 0o755); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", filepath.Join(baseDir, "piece_index.db"))
+	if err != nil {
+		return nil, err
+	}
+
+	adapter := &FileAdapter{baseDir: baseDir, index: db}
+	if err := adapter.migrate(); err != nil {
+		return nil, err
+	}
+
+	return adapter, nil
+}
+
+func (f *FileAdapter) migrate() error {
+	_, err := f.index.Exec(`
+CREATE TABLE IF NOT EXISTS piece_expirations (
+	piece_cid TEXT PRIMARY KEY,
+	dataset_id TEXT NOT NULL,
+	size_bytes INTEGER NOT NULL,
+	created_at TEXT NOT NULL,
+	path TEXT NOT NULL
+);
+`)
+	return err
+}
+
+func (f *FileAdapter) Close() error {
+	return f.index.Close()
+}
+
+func (f *FileAdapter) EnsureDataSet(ctx context.Context, meta DataSetMeta) (DataSetID, error) {
+	dataSetID := DataSetID("ds-" + uuid.New().String())
+	if err := os.MkdirAll(f.datasetDir(dataSetID), 0o755); err != nil {
+		return "", err
+	}
+	return dataSetID, nil
+}
+
+func (f *FileAdapter) Upload(ctx context.Context, dataSetID DataSetID, data []byte, opts UploadOptions) (*UploadResult, error) {
+	sum := sha256.Sum256(data)
+	pieceCID := PieceCID(hex.EncodeToString(sum[:]))
+
+	header := pieceHeader{
+		PieceCID:     string(pieceCID),
+		DataSetID:    string(dataSetID),
+		OriginalSize: int64(len(data)),
+		SHA256:       sum,
+		CreatedAt:    time.Now().UTC(),
+		Wallet:       opts.Wallet,
+	}
+
+	if err := os.MkdirAll(f.datasetDir(dataSetID), 0o755); err != nil {
+		return nil, err
+	}
+
+	path := f.piecePath(dataSetID, pieceCID)
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if err := writePieceFile(file, currentPieceFormat, header, data); err != nil {
+		return nil, err
+	}
+
+	if _, err := f.index.Exec(`
+INSERT INTO piece_expirations(piece_cid, dataset_id, size_bytes, created_at, path)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT(piece_cid) DO UPDATE SET
+	dataset_id = excluded.dataset_id,
+	size_bytes = excluded.size_bytes,
+	created_at = excluded.created_at,
+	path = excluded.path
+`, string(pieceCID), string(dataSetID), int64(len(data)), header.CreatedAt.Format(time.RFC3339Nano), path); err != nil {
+		return nil, err
+	}
+
+	return &UploadResult{PieceCID: pieceCID, Size: len(data)}, nil
+}
+
+func (f *FileAdapter) Download(ctx context.Context, pieceCID PieceCID) ([]byte, error) {
+	path, err := f.locate(pieceCID)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	_, payload, err := readPieceFile(raw)
+	if err != nil {
+		return nil, fmt.Errorf("piece %s: %w", pieceCID, err)
+	}
+
+	return payload, nil
+}
+
+func (f *FileAdapter) Delete(ctx context.Context, pieceCID PieceCID) error {
+	path, err := f.locate(pieceCID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil // already gone
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	_, err = f.index.Exec(`DELETE FROM piece_expirations WHERE piece_cid = ?`, string(pieceCID))
+	return err
+}
+
+// locate resolves a piece CID to its file path via the index. If the index
+// is missing the row (e.g. it was wiped and not yet rebuilt), Download
+// fails and Delete treats the piece as already gone (it returns nil and
+// leaves any file on disk); neither falls back to a directory walk per
+// call. RebuildIndex exists for the bulk-recovery case.
+func (f *FileAdapter) locate(pieceCID PieceCID) (string, error) {
+	var path string
+	err := f.index.QueryRow(`SELECT path FROM piece_expirations WHERE piece_cid = ?`, string(pieceCID)).Scan(&path)
+	return path, err
+}
+
+func (f *FileAdapter) datasetDir(dataSetID DataSetID) string {
+	return filepath.Join(f.baseDir, string(dataSetID))
+}
+
+func (f *FileAdapter) piecePath(dataSetID DataSetID, pieceCID PieceCID) string {
+	return filepath.Join(f.datasetDir(dataSetID), string(pieceCID)+".piece")
+}
+
+// RebuildIndex walks the piece directory and repopulates piece_expirations
+// entirely from piece file headers, proving the DB is a cache and not the
+// source of truth: delete it and this gets you back to where you started.
+func (f *FileAdapter) RebuildIndex(ctx context.Context) error {
+	if _, err := f.index.Exec(`DELETE FROM piece_expirations`); err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(f.baseDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".piece" {
+			return nil
+		}
+
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		header, _, err := readPieceFile(raw)
+		if err != nil {
+			return fmt.Errorf("rebuild: %s: %w", path, err)
+		}
+
+		_, err = f.index.Exec(`
+INSERT INTO piece_expirations(piece_cid, dataset_id, size_bytes, created_at, path)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT(piece_cid) DO UPDATE SET
+	dataset_id = excluded.dataset_id,
+	size_bytes = excluded.size_bytes,
+	created_at = excluded.created_at,
+	path = excluded.path
+`, header.PieceCID, header.DataSetID, header.OriginalSize, header.CreatedAt.Format(time.RFC3339Nano), path)
+		return err
+	})
+}
+
+// MigrateAll rewrites every v1 piece file in place as v2, so a bump of
+// currentPieceFormat doesn't strand old data: old pieces stay readable
+// either way, but running this brings them up to the latest header shape.
+func (f *FileAdapter) MigrateAll(ctx context.Context) error {
+	return filepath.WalkDir(f.baseDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".piece" {
+			return nil
+		}
+
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		version, err := pieceFileVersion(raw)
+		if err != nil {
+			return fmt.Errorf("migrate: %s: %w", path, err)
+		}
+		if version == currentPieceFormat {
+			return nil
+		}
+
+		header, payload, err := readPieceFile(raw)
+		if err != nil {
+			return fmt.Errorf("migrate: %s: %w", path, err)
+		}
+
+		// Write to a temp file in the same directory and rename over the
+		// original instead of truncating it in place: a crash partway through
+		// os.Create+writePieceFile would otherwise leave the piece with the
+		// original bytes already gone and the new ones incomplete.
+		tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".migrate-*")
+		if err != nil {
+			return err
+		}
+		tmpPath := tmp.Name()
+
+		if err := writePieceFile(tmp, currentPieceFormat, header, payload); err != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+			return err
+		}
+		if err := tmp.Close(); err != nil {
+			os.Remove(tmpPath)
+			return err
+		}
+
+		return os.Rename(tmpPath, path)
+	})
+}
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// pieceMagic identifies a file as a Qave piece file, so Download can reject
+// garbage (or a file from an unrelated directory someone pointed baseDir
+// at) before trusting anything else in it.
+var pieceMagic = [4]byte{'Q', 'A', 'V', 'E'}
+
+// Storage-format versions. v1 shipped without a Wallet field in the header;
+// v2 added it. Both are readable; only v2 is written going forward.
+const (
+	pieceFormatV1 uint16 = 1
+	pieceFormatV2 uint16 = 2
+
+	currentPieceFormat = pieceFormatV2
+)
+
+// pieceHeaderV1 is kept only so MigrateAll and Download can still decode
+// pieces written by the original format.
+type pieceHeaderV1 struct {
+	PieceCID     string
+	DataSetID    string
+	OriginalSize int64
+	SHA256       [32]byte
+	CreatedAt    time.Time
+}
+
+// pieceHeader is the v2 on-disk header: everything needed to answer "what
+// is this piece, whose is it, and has it been tampered with" without
+// touching any database. This is the source of truth for piece metadata;
+// the SQLite piece index is only a cache for fast expiry scans.
+type pieceHeader struct {
+	PieceCID     string
+	DataSetID    string
+	OriginalSize int64
+	SHA256       [32]byte
+	CreatedAt    time.Time
+	Wallet       string
+}
+
+func (h pieceHeader) asV1() pieceHeaderV1 {
+	return pieceHeaderV1{
+		PieceCID:     h.PieceCID,
+		DataSetID:    h.DataSetID,
+		OriginalSize: h.OriginalSize,
+		SHA256:       h.SHA256,
+		CreatedAt:    h.CreatedAt,
+	}
+}
+
+// writePieceFile lays out: magic | version (uint16) | header length
+// (uint32) | JSON header | payload. The header is length-prefixed rather
+// than fixed-size so it can grow across versions without relayout of the
+// rest of the file.
+func writePieceFile(w io.Writer, version uint16, header pieceHeader, payload []byte) error {
+	var headerBytes []byte
+	var err error
+
+	switch version {
+	case pieceFormatV1:
+		headerBytes, err = json.Marshal(header.asV1())
+	case pieceFormatV2:
+		headerBytes, err = json.Marshal(header)
+	default:
+		return fmt.Errorf("unsupported storage format version %d", version)
+	}
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	buf.Write(pieceMagic[:])
+	binary.Write(buf, binary.BigEndian, version)
+	binary.Write(buf, binary.BigEndian, uint32(len(headerBytes)))
+	buf.Write(headerBytes)
+	buf.Write(payload)
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// readPieceFile parses a piece file and verifies the payload against the
+// header's recorded SHA-256, rejecting the piece on any mismatch so a
+// corrupted or truncated file is never silently served.
+func readPieceFile(data []byte) (pieceHeader, []byte, error) {
+	if len(data) < len(pieceMagic)+2+4 {
+		return pieceHeader{}, nil, errors.New("piece file too short")
+	}
+
+	if !bytes.Equal(data[:len(pieceMagic)], pieceMagic[:]) {
+		return pieceHeader{}, nil, errors.New("bad piece magic")
+	}
+	offset := len(pieceMagic)
+
+	version := binary.BigEndian.Uint16(data[offset : offset+2])
+	offset += 2
+
+	headerLen := binary.BigEndian.Uint32(data[offset : offset+4])
+	offset += 4
+
+	if offset+int(headerLen) > len(data) {
+		return pieceHeader{}, nil, errors.New("truncated piece header")
+	}
+	headerBytes := data[offset : offset+int(headerLen)]
+	offset += int(headerLen)
+	payload := data[offset:]
+
+	var header pieceHeader
+	switch version {
+	case pieceFormatV1:
+		var v1 pieceHeaderV1
+		if err := json.Unmarshal(headerBytes, &v1); err != nil {
+			return pieceHeader{}, nil, fmt.Errorf("decode v1 header: %w", err)
+		}
+		header = pieceHeader{
+			PieceCID:     v1.PieceCID,
+			DataSetID:    v1.DataSetID,
+			OriginalSize: v1.OriginalSize,
+			SHA256:       v1.SHA256,
+			CreatedAt:    v1.CreatedAt,
+		}
+	case pieceFormatV2:
+		if err := json.Unmarshal(headerBytes, &header); err != nil {
+			return pieceHeader{}, nil, fmt.Errorf("decode v2 header: %w", err)
+		}
+	default:
+		return pieceHeader{}, nil, fmt.Errorf("unsupported storage format version %d", version)
+	}
+
+	if sha256.Sum256(payload) != header.SHA256 {
+		return pieceHeader{}, nil, errors.New("payload does not match header checksum")
+	}
+
+	return header, payload, nil
+}
+
+func pieceFileVersion(data []byte) (uint16, error) {
+	if len(data) < len(pieceMagic)+2 {
+		return 0, errors.New("piece file too short")
+	}
+	if !bytes.Equal(data[:len(pieceMagic)], pieceMagic[:]) {
+		return 0, errors.New("bad piece magic")
+	}
+	return binary.BigEndian.Uint16(data[len(pieceMagic) : len(pieceMagic)+2]), nil
+}